@@ -18,21 +18,44 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	connect "connectrpc.com/connect"
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	aquariumVersion "github.com/adobe/packer-plugin-aquarium/version"
+	"github.com/hashicorp/go-version"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Supported RPC wire protocols
+const (
+	ProtocolConnect = "connect"
+	ProtocolGRPC    = "grpc"
+	ProtocolGRPCWeb = "grpc-web"
 )
 
 // API Client for AquariumFish
 type APIClient struct {
 	BaseURL string
 
-	// underlying HTTP client used by connect clients (injects Basic Auth)
+	// underlying HTTP client used by connect clients (injects Authorization header)
 	httpClient connectHTTPClient
+	// automatic retries of transient RPC failures
+	retries      int
+	retryBackoff time.Duration
+	// deadline for a single unary RPC call
+	requestTimeout time.Duration
+	// wire protocol used by the RPC clients: connect, grpc or grpc-web
+	protocol string
+	// version of the target server API, nil means the plugin one
+	apiVersion *version.Version
 
 	// generated RPC clients
 	labelClient     aquariumv2connect.LabelServiceClient
@@ -40,46 +63,264 @@ type APIClient struct {
 	userClient      aquariumv2connect.UserServiceClient
 	gateProxySSH    aquariumv2connect.GateProxySSHServiceClient
 	streamingClient aquariumv2connect.StreamingServiceClient
+	nodeClient      aquariumv2connect.NodeServiceClient
+}
+
+// APIAuth describes the credentials used to authenticate on AquariumFish API
+type APIAuth struct {
+	Username string
+	Password string
+	// Token is sent as Bearer and takes precedence over Username/Password when set
+	Token string
+	// OAuth2 enables client-credentials grant, the received token is sent as Bearer
+	OAuth2 *clientcredentials.Config
+	// OAuth2HTTPClient requests the token, the API HTTP client is used if not set. It's needed when
+	// the API one could reach only the Fish endpoint, like the unix socket or h2c one
+	OAuth2HTTPClient *http.Client
+}
+
+// header returns the Authorization header value for the configured auth mode
+func (a APIAuth) header() string {
+	if a.Token != "" {
+		return bearerAuth(a.Token)
+	}
+	if a.Username != "" || a.Password != "" {
+		return basicAuth(a.Username, a.Password)
+	}
+	return ""
+}
+
+// APIClientOption configures optional behavior of the APIClient
+type APIClientOption func(*APIClient)
+
+// WithUserAgent sets User-Agent header sent with every request
+func WithUserAgent(userAgent string) APIClientOption {
+	return func(c *APIClient) {
+		c.httpClient.userAgent = userAgent
+	}
+}
+
+// WithRetry enables retries of the idempotent RPC calls failed with transient errors, delay between the
+// attempts starts from backoff and grows exponentially
+func WithRetry(retries int, backoff time.Duration) APIClientOption {
+	return func(c *APIClient) {
+		c.retries = retries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithRequestTimeout limits the duration of every single unary RPC call attempt
+func WithRequestTimeout(timeout time.Duration) APIClientOption {
+	return func(c *APIClient) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithProtocol selects the wire protocol of the RPC clients: "connect" (default), "grpc" or
+// "grpc-web"
+func WithProtocol(protocol string) APIClientOption {
+	return func(c *APIClient) {
+		c.protocol = protocol
+	}
 }
 
 // NewAPIClient creates a new API client
-func NewAPIClient(baseURL, username, password string, httpClient *http.Client) *APIClient {
+func NewAPIClient(baseURL string, auth APIAuth, httpClient *http.Client, opts ...APIClientOption) *APIClient {
 	baseURL = strings.TrimSuffix(baseURL, "/")
 
-	// Prepare a connect-compatible HTTP client that injects Basic auth
-	auth := basicAuth(username, password)
-	ch := connectHTTPClient{base: httpClient, authHeader: auth}
+	// Prepare a connect-compatible HTTP client that injects Basic or Bearer auth
+	ch := connectHTTPClient{base: httpClient, authHeader: auth.header(), userAgent: DefaultUserAgent("")}
+	if auth.Token == "" && auth.OAuth2 != nil {
+		// Token source caches the token and refreshes it before expiry
+		tokenClient := auth.OAuth2HTTPClient
+		if tokenClient == nil {
+			tokenClient = httpClient
+		}
+		tokenCtx := context.WithValue(context.Background(), oauth2.HTTPClient, tokenClient)
+		ch.tokenSource = auth.OAuth2.TokenSource(tokenCtx)
+	}
 
 	c := &APIClient{BaseURL: baseURL, httpClient: ch}
-	c.labelClient = aquariumv2connect.NewLabelServiceClient(ch, baseURL)
-	c.appClient = aquariumv2connect.NewApplicationServiceClient(ch, baseURL)
-	c.userClient = aquariumv2connect.NewUserServiceClient(ch, baseURL)
-	c.gateProxySSH = aquariumv2connect.NewGateProxySSHServiceClient(ch, baseURL)
-	c.streamingClient = aquariumv2connect.NewStreamingServiceClient(ch, baseURL)
+	for _, opt := range opts {
+		opt(c)
+	}
+	ch = c.httpClient
+
+	// Retry is the outer interceptor so the request timeout is applied to each attempt
+	interceptors := []connect.Interceptor{newCompatibilityInterceptor()}
+	if c.retries > 0 {
+		interceptors = append(interceptors, newRetryInterceptor(c.retries, c.retryBackoff))
+	}
+	if c.requestTimeout > 0 {
+		interceptors = append(interceptors, newTimeoutInterceptor(c.requestTimeout))
+	}
+	if debugLogging {
+		// The innermost one to log each of the attempts separately
+		interceptors = append(interceptors, loggingInterceptor{})
+	}
+	clientOpts := []connect.ClientOption{connect.WithInterceptors(interceptors...)}
+	switch c.protocol {
+	case ProtocolGRPC:
+		clientOpts = append(clientOpts, connect.WithGRPC())
+	case ProtocolGRPCWeb:
+		clientOpts = append(clientOpts, connect.WithGRPCWeb())
+	}
+
+	c.labelClient = aquariumv2connect.NewLabelServiceClient(ch, baseURL, clientOpts...)
+	c.appClient = aquariumv2connect.NewApplicationServiceClient(ch, baseURL, clientOpts...)
+	c.userClient = aquariumv2connect.NewUserServiceClient(ch, baseURL, clientOpts...)
+	c.gateProxySSH = aquariumv2connect.NewGateProxySSHServiceClient(ch, baseURL, clientOpts...)
+	c.streamingClient = aquariumv2connect.NewStreamingServiceClient(ch, baseURL, clientOpts...)
+	c.nodeClient = aquariumv2connect.NewNodeServiceClient(ch, baseURL, clientOpts...)
 	return c
 }
 
-// connectHTTPClient injects Authorization header for all requests
+// NewConfiguredAPIClient creates the API client with the endpoint, TLS, proxy, transport and auth
+// settings of the config, so it could be used outside of the builder steps
+func NewConfiguredAPIClient(c *Config) (*APIClient, error) {
+	httpClient, err := NewHTTPClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return configuredAPIClient(c, httpClient)
+}
+
+// configuredAPIClient creates the API client on the HTTP client shared by the build
+func configuredAPIClient(c *Config, httpClient *http.Client) (*APIClient, error) {
+	auth := APIAuth{
+		Username: c.Username,
+		Password: c.Password,
+		Token:    c.Token,
+	}
+	if c.OAuthTokenURL != "" {
+		auth.OAuth2 = &clientcredentials.Config{
+			ClientID:     c.OAuthClientID,
+			ClientSecret: c.OAuthClientSecret,
+			TokenURL:     c.OAuthTokenURL,
+			Scopes:       c.OAuthScopes,
+		}
+		tokenClient, err := newTokenHTTPClient(c)
+		if err != nil {
+			return nil, err
+		}
+		auth.OAuth2HTTPClient = tokenClient
+	}
+
+	// The config is not prepared when it's created by the data sources, so parsing the timeout here
+	requestTimeout := c.requestTimeoutDuration
+	if requestTimeout == 0 && c.RequestTimeout != "" {
+		var err error
+		if requestTimeout, err = time.ParseDuration(c.RequestTimeout); err != nil {
+			return nil, fmt.Errorf("invalid request_timeout: %v", err)
+		}
+	}
+	apiVersion := c.apiVersion
+	if apiVersion == nil && c.APIVersion != "" {
+		var err error
+		if apiVersion, err = version.NewVersion(c.APIVersion); err != nil {
+			return nil, fmt.Errorf("invalid api_version: %v", err)
+		}
+	}
+
+	opts := []APIClientOption{
+		WithRetry(c.RequestRetries, c.retryBackoffDuration),
+		WithRequestTimeout(requestTimeout),
+		WithProtocol(c.Protocol),
+		WithAPIVersion(apiVersion),
+	}
+	if c.UserAgent != "" {
+		opts = append(opts, WithUserAgent(c.UserAgent))
+	}
+	return NewAPIClient(APIEndpointURL(c.Endpoint, c.APIPath), auth, httpClient, opts...), nil
+}
+
+// DefaultAPIPath is the path of the RPC services on the Fish endpoint
+const DefaultAPIPath = "grpc"
+
+// APIEndpointURL returns the base URL of the API, apiPath is used if endpoint has no path, the slashes
+// around the path are normalized so "/", "/api/" and "api" are the same
+func APIEndpointURL(endpoint, apiPath string) string {
+	// The socket path is used only by the transport, so connect clients need just some http:// base URL
+	if _, ok := unixSocketPath(endpoint); ok {
+		return unixSocketBaseURL + joinAPIPath(apiPath)
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	if strings.Trim(endpointURL.Path, "/") == "" {
+		endpointURL.Path = joinAPIPath(apiPath)
+	} else {
+		endpointURL.Path = joinAPIPath(endpointURL.Path)
+	}
+	endpointURL.RawPath = ""
+	return endpointURL.String()
+}
+
+// joinAPIPath returns the path with single leading slash and without trailing one
+func joinAPIPath(apiPath string) string {
+	if apiPath = strings.Trim(apiPath, "/"); apiPath == "" {
+		return ""
+	}
+	return "/" + apiPath
+}
+
+// DefaultUserAgent returns User-Agent with the plugin version and Packer core version if known
+func DefaultUserAgent(packerCoreVersion string) string {
+	userAgent := "packer-plugin-aquarium/" + aquariumVersion.PluginVersion.FormattedVersion()
+	if packerCoreVersion != "" {
+		userAgent += " packer/" + packerCoreVersion
+	}
+	return userAgent
+}
+
+// connectHTTPClient injects Authorization and User-Agent headers for all requests
 type connectHTTPClient struct {
-	base       *http.Client
-	authHeader string
+	base        *http.Client
+	authHeader  string
+	tokenSource oauth2.TokenSource
+	userAgent   string
 }
 
 func (c connectHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	if c.authHeader != "" {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.tokenSource != nil {
+		token, err := c.tokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("unable to obtain OAuth2 token: %v", err)
+		}
+		req.Header.Set("Authorization", bearerAuth(token.AccessToken))
+	} else if c.authHeader != "" {
 		req.Header.Set("Authorization", c.authHeader)
 	}
 	return c.base.Do(req)
 }
 
+// EnsureToken verifies the OAuth2 token could be obtained, no-op for static credentials
+func (c *APIClient) EnsureToken() error {
+	if c.httpClient.tokenSource == nil {
+		return nil
+	}
+	_, err := c.httpClient.tokenSource.Token()
+	return err
+}
+
 func basicAuth(user, pass string) string {
 	token := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
 	return "Basic " + token
 }
 
+func bearerAuth(token string) string {
+	return "Bearer " + token
+}
+
 // GetLabels retrieves labels, optionally filtered by name and version
+//
+// Fish list RPC has no pagination and returns all the matching labels in a single response
 func (c *APIClient) GetLabels(ctx context.Context, name, version string) ([]*aquariumv2.Label, error) {
-	req := aquariumv2.LabelServiceListRequest{}
+	req := &aquariumv2.LabelServiceListRequest{}
 	if name != "" {
 		req.Name = &name
 	}
@@ -93,9 +334,104 @@ func (c *APIClient) GetLabels(ctx context.Context, name, version string) ([]*aqu
 	return resp.Msg.GetData(), nil
 }
 
+// GetLabel retrieves the label by UID
+func (c *APIClient) GetLabel(ctx context.Context, uid string) (*aquariumv2.Label, error) {
+	resp, err := c.labelClient.Get(ctx, connectRequest(&aquariumv2.LabelServiceGetRequest{LabelUid: uid}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetData(), nil
+}
+
+// NodeCapacity describes the total resources of the Fish node
+type NodeCapacity struct {
+	UID  string
+	Name string
+	// Number of CPU cores and RAM in GB, like in the label definition resources
+	CPU uint32
+	RAM uint32
+}
+
+// ClusterCapacity describes the Fish nodes visible to the user
+type ClusterCapacity struct {
+	Nodes []NodeCapacity
+}
+
+// GetClusterCapacity retrieves the total resources of the cluster nodes
+//
+// Fish doesn't report the node resources used by the other applications or the overbook settings,
+// so it's only the upper bound of what the node could allocate
+func (c *APIClient) GetClusterCapacity(ctx context.Context) (*ClusterCapacity, error) {
+	resp, err := c.nodeClient.List(ctx, connectRequest(&aquariumv2.NodeServiceListRequest{}))
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := &ClusterCapacity{}
+	for _, node := range resp.Msg.GetData() {
+		def := node.GetDefinition()
+		// Depending on platform CPU info is reported per core or per physical CPU
+		var cpu uint32
+		for _, info := range def.GetCpu() {
+			cpu += uint32(max(info.GetCores(), 1))
+		}
+		capacity.Nodes = append(capacity.Nodes, NodeCapacity{
+			UID:  node.GetUid(),
+			Name: node.GetName(),
+			CPU:  cpu,
+			RAM:  uint32((def.GetMemory().GetTotal() + 1<<30 - 1) >> 30),
+		})
+	}
+	return capacity, nil
+}
+
+// CreateLabel creates a new label, Fish assigns the UID of the label
+func (c *APIClient) CreateLabel(ctx context.Context, label *aquariumv2.Label) (*aquariumv2.Label, error) {
+	resp, err := c.labelClient.Create(ctx, connectRequest(&aquariumv2.LabelServiceCreateRequest{Label: label}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetData(), nil
+}
+
+// DeleteLabel removes the label by UID
+func (c *APIClient) DeleteLabel(ctx context.Context, uid string) error {
+	_, err := c.labelClient.Remove(ctx, connectRequest(&aquariumv2.LabelServiceRemoveRequest{LabelUid: uid}))
+	return err
+}
+
 // CreateApplication creates a new application
 func (c *APIClient) CreateApplication(ctx context.Context, app *aquariumv2.Application) (*aquariumv2.Application, error) {
-	resp, err := c.appClient.Create(ctx, connectRequest(aquariumv2.ApplicationServiceCreateRequest{Application: app}))
+	resp, err := c.appClient.Create(ctx, connectRequest(&aquariumv2.ApplicationServiceCreateRequest{Application: app}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetData(), nil
+}
+
+// ListApplications retrieves the applications accepted by the filter, nil filter accepts all of them
+//
+// Fish list RPC has no server-side filtering, so the filter is applied on the client side
+func (c *APIClient) ListApplications(ctx context.Context, filter func(*aquariumv2.Application) bool) ([]*aquariumv2.Application, error) {
+	resp, err := c.appClient.List(ctx, connectRequest(&aquariumv2.ApplicationServiceListRequest{}))
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		return resp.Msg.GetData(), nil
+	}
+	var apps []*aquariumv2.Application
+	for _, app := range resp.Msg.GetData() {
+		if filter(app) {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// GetApplication retrieves the application by UID
+func (c *APIClient) GetApplication(ctx context.Context, uid string) (*aquariumv2.Application, error) {
+	resp, err := c.appClient.Get(ctx, connectRequest(&aquariumv2.ApplicationServiceGetRequest{ApplicationUid: uid}))
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +440,7 @@ func (c *APIClient) CreateApplication(ctx context.Context, app *aquariumv2.Appli
 
 // GetApplicationState retrieves the current state of an application
 func (c *APIClient) GetApplicationState(ctx context.Context, uid string) (*aquariumv2.ApplicationState, error) {
-	resp, err := c.appClient.GetState(ctx, connectRequest(aquariumv2.ApplicationServiceGetStateRequest{ApplicationUid: uid}))
+	resp, err := c.appClient.GetState(ctx, connectRequest(&aquariumv2.ApplicationServiceGetStateRequest{ApplicationUid: uid}))
 	if err != nil {
 		return nil, err
 	}
@@ -113,21 +449,21 @@ func (c *APIClient) GetApplicationState(ctx context.Context, uid string) (*aquar
 
 // GetApplicationResource retrieves the application resource
 func (c *APIClient) GetApplicationResource(ctx context.Context, uid string) (*aquariumv2.ApplicationResource, error) {
-	resp, err := c.appClient.GetResource(ctx, connectRequest(aquariumv2.ApplicationServiceGetResourceRequest{ApplicationUid: uid}))
+	resp, err := c.appClient.GetResource(ctx, connectRequest(&aquariumv2.ApplicationServiceGetResourceRequest{ApplicationUid: uid}))
 	if err != nil {
 		return nil, err
 	}
 	return resp.Msg.GetData(), nil
 }
 
-// GetApplicationResourceAccess retrieves SSH access credentials
-func (c *APIClient) GetApplicationResourceAccess(ctx context.Context, resourceUID string) (*aquariumv2.GateProxySSHAccess, error) {
-	// Receiving static credential because Packer has no proper mechanism to use OTP
-	static := true
-	resp, err := c.gateProxySSH.GetResourceAccess(ctx, connectRequest(aquariumv2.GateProxySSHServiceGetResourceAccessRequest{
-		ApplicationResourceUid: resourceUID,
-		Static:                 &static,
-	}))
+// GetApplicationResourceAccess retrieves SSH access credentials, static ones could be used multiple
+// times while non-static (OTP) are valid only for a single connection
+func (c *APIClient) GetApplicationResourceAccess(ctx context.Context, resourceUID string, static bool) (*aquariumv2.GateProxySSHAccess, error) {
+	req := &aquariumv2.GateProxySSHServiceGetResourceAccessRequest{ApplicationResourceUid: resourceUID}
+	if c.supports(staticAccessVersion) {
+		req.Static = &static
+	}
+	resp, err := c.gateProxySSH.GetResourceAccess(ctx, connectRequest(req))
 	if err != nil {
 		return nil, err
 	}
@@ -136,13 +472,13 @@ func (c *APIClient) GetApplicationResourceAccess(ctx context.Context, resourceUI
 
 // DeallocateApplication triggers application deallocation
 func (c *APIClient) DeallocateApplication(ctx context.Context, uid string) error {
-	_, err := c.appClient.Deallocate(ctx, connectRequest(aquariumv2.ApplicationServiceDeallocateRequest{ApplicationUid: uid}))
+	_, err := c.appClient.Deallocate(ctx, connectRequest(&aquariumv2.ApplicationServiceDeallocateRequest{ApplicationUid: uid}))
 	return err
 }
 
 // CreateApplicationTask creates a new application task
 func (c *APIClient) CreateApplicationTask(ctx context.Context, task *aquariumv2.ApplicationTask) (*aquariumv2.ApplicationTask, error) {
-	resp, err := c.appClient.CreateTask(ctx, connectRequest(aquariumv2.ApplicationServiceCreateTaskRequest{Task: task}))
+	resp, err := c.appClient.CreateTask(ctx, connectRequest(&aquariumv2.ApplicationServiceCreateTaskRequest{Task: task}))
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +487,16 @@ func (c *APIClient) CreateApplicationTask(ctx context.Context, task *aquariumv2.
 
 // GetApplicationTask retrieves an application task
 func (c *APIClient) GetApplicationTask(ctx context.Context, taskUID string) (*aquariumv2.ApplicationTask, error) {
-	resp, err := c.appClient.GetTask(ctx, connectRequest(aquariumv2.ApplicationServiceGetTaskRequest{ApplicationTaskUid: taskUID}))
+	resp, err := c.appClient.GetTask(ctx, connectRequest(&aquariumv2.ApplicationServiceGetTaskRequest{ApplicationTaskUid: taskUID}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetData(), nil
+}
+
+// ListApplicationTasks retrieves all the tasks of the application
+func (c *APIClient) ListApplicationTasks(ctx context.Context, appUID string) ([]*aquariumv2.ApplicationTask, error) {
+	resp, err := c.appClient.ListTask(ctx, connectRequest(&aquariumv2.ApplicationServiceListTaskRequest{ApplicationUid: appUID}))
 	if err != nil {
 		return nil, err
 	}
@@ -160,43 +505,102 @@ func (c *APIClient) GetApplicationTask(ctx context.Context, taskUID string) (*aq
 
 // Subscribe opens a server stream for database change notifications
 func (c *APIClient) Subscribe(ctx context.Context, types []aquariumv2.SubscriptionType) (*streamWrapper, error) {
-	req := aquariumv2.StreamingServiceSubscribeRequest{SubscriptionTypes: types}
-	stream, err := c.streamingClient.Subscribe(ctx, connectRequest(req))
-	if err != nil {
+	s := &streamWrapper{client: c, ctx: ctx, types: types}
+	if err := s.subscribe(); err != nil {
 		return nil, err
 	}
-	return &streamWrapper{stream: stream}, nil
+	return s, nil
+}
+
+// maxStreamReconnects limits the consecutive attempts to restore the broken subscription stream
+const maxStreamReconnects = 5
+
+// subscriptionStream is the updates stream stored in the state as "subscribe_stream"
+type subscriptionStream interface {
+	Receive() (*aquariumv2.StreamingServiceSubscribeResponse, error)
+	Close() error
+	Reconnects() int
 }
 
+// streamWrapper re-subscribes with the same types when the stream is broken by transient error
 type streamWrapper struct {
+	client *APIClient
+	ctx    context.Context
+	types  []aquariumv2.SubscriptionType
 	stream *connect.ServerStreamForClient[aquariumv2.StreamingServiceSubscribeResponse]
+
+	// failed attempts since the last received message and total amount of reconnections
+	attempts   int
+	reconnects int
+}
+
+func (s *streamWrapper) subscribe() error {
+	req := &aquariumv2.StreamingServiceSubscribeRequest{SubscriptionTypes: s.types}
+	stream, err := s.client.streamingClient.Subscribe(s.ctx, connectRequest(req))
+	if err != nil {
+		return err
+	}
+	s.stream = stream
+	return nil
 }
 
 func (s *streamWrapper) Receive() (*aquariumv2.StreamingServiceSubscribeResponse, error) {
-	if !s.stream.Receive() {
-		return nil, s.stream.Err()
+	for {
+		if s.stream.Receive() {
+			s.attempts = 0
+			return s.stream.Msg(), nil
+		}
+		err := s.stream.Err()
+		if err == nil || s.attempts >= maxStreamReconnects || !isTransientError(s.ctx, err) {
+			return nil, err
+		}
+		s.stream.Close()
+
+		// Exponential backoff on consecutive failures, same as for the unary calls
+		delay := s.client.retryBackoff
+		if delay <= 0 {
+			delay = time.Second
+		}
+		delay = min(delay<<s.attempts, maxRetryBackoff)
+		s.attempts++
+		select {
+		case <-s.ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+
+		logInfo("Subscription stream failed: %v, reconnecting (attempt %d/%d)", err, s.attempts, maxStreamReconnects)
+		if subErr := s.subscribe(); subErr != nil {
+			return nil, subErr
+		}
+		s.reconnects++
 	}
-	return s.stream.Msg(), nil
 }
 
+// Close drains the rest of the response and releases the underlying HTTP stream
 func (s *streamWrapper) Close() error { return s.stream.Close() }
 
+// Reconnects returns how many times the stream was re-subscribed after the transient errors
+func (s *streamWrapper) Reconnects() int { return s.reconnects }
+
 // connectRequest is a small helper to avoid importing connect in every caller
-// Note: some generated clients expect *connect.Request[T] where T is a concrete message type
-// Using pointer where the client expects non-pointer causes a mismatch. Provide overloads as needed.
-func connectRequest[T any](msg T) *connect.Request[T] { return connect.NewRequest[T](&msg) }
+// Note: the message is passed by pointer because protobuf messages contain a mutex and must
+// not be copied.
+func connectRequest[T any](msg *T) *connect.Request[T] { return connect.NewRequest[T](msg) }
 
-// ParseSSHAddress parses SSH address into host and port
+// ParseSSHAddress parses SSH address into host and port, IPv6 host should be in brackets
 func ParseSSHAddress(addr string) (string, int, error) {
-	parts := strings.Split(addr, ":")
-	if len(parts) != 2 {
-		return "", 0, fmt.Errorf("invalid SSH address format: %s", addr)
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid SSH address format: %s: %v", addr, err)
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("empty host in SSH address: %s", addr)
 	}
 
-	host := parts[0]
-	port, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return "", 0, fmt.Errorf("invalid port in SSH address: %s", parts[1])
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in SSH address: %s", portStr)
 	}
 
 	return host, port, nil
@@ -204,7 +608,7 @@ func ParseSSHAddress(addr string) (string, int, error) {
 
 // GetCurrentUser retrieves the current authenticated user (used as connectivity check)
 func (c *APIClient) GetCurrentUser(ctx context.Context) (*aquariumv2.User, error) {
-	resp, err := c.userClient.GetMe(ctx, connectRequest(aquariumv2.UserServiceGetMeRequest{}))
+	resp, err := c.userClient.GetMe(ctx, connectRequest(&aquariumv2.UserServiceGetMeRequest{}))
 	if err != nil {
 		return nil, err
 	}
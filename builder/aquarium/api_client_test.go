@@ -0,0 +1,338 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/go-version"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestParseSSHAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		host string
+		port int
+	}{
+		{"127.0.0.1:22", "127.0.0.1", 22},
+		{"[2001:db8::1]:2222", "2001:db8::1", 2222},
+		{"[::1]:22", "::1", 22},
+		{"proxy.example.com:1122", "proxy.example.com", 1122},
+	}
+	for _, tt := range tests {
+		host, port, err := ParseSSHAddress(tt.addr)
+		if err != nil {
+			t.Errorf("ParseSSHAddress(%q) unexpected error: %v", tt.addr, err)
+			continue
+		}
+		if host != tt.host || port != tt.port {
+			t.Errorf("ParseSSHAddress(%q) = %q, %d; expected %q, %d", tt.addr, host, port, tt.host, tt.port)
+		}
+	}
+}
+
+func TestParseSSHAddress_Malformed(t *testing.T) {
+	for _, addr := range []string{
+		"",
+		"127.0.0.1",
+		"2001:db8::1:22",
+		"[2001:db8::1]",
+		"host:port",
+		"host:0",
+		"host:70000",
+		":22",
+	} {
+		if host, port, err := ParseSSHAddress(addr); err == nil {
+			t.Errorf("ParseSSHAddress(%q) = %q, %d; expected error", addr, host, port)
+		}
+	}
+}
+
+type testStreamingService struct {
+	aquariumv2connect.UnimplementedStreamingServiceHandler
+	// Amount of the first subscriptions to be reset by the server
+	resets int
+	calls  int
+	types  [][]aquariumv2.SubscriptionType
+}
+
+func (s *testStreamingService) Subscribe(ctx context.Context, req *connect.Request[aquariumv2.StreamingServiceSubscribeRequest], stream *connect.ServerStream[aquariumv2.StreamingServiceSubscribeResponse]) error {
+	s.calls++
+	s.types = append(s.types, req.Msg.GetSubscriptionTypes())
+	if s.calls <= s.resets {
+		return connect.NewError(connect.CodeUnavailable, nil)
+	}
+	return stream.Send(&aquariumv2.StreamingServiceSubscribeResponse{ObjectType: aquariumv2.SubscriptionType_SUBSCRIPTION_TYPE_APPLICATION})
+}
+
+func TestStreamWrapper_Reconnects(t *testing.T) {
+	streaming := &testStreamingService{resets: 2}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewStreamingServiceHandler(streaming))
+	})
+	client.retryBackoff = time.Millisecond
+
+	types := []aquariumv2.SubscriptionType{aquariumv2.SubscriptionType_SUBSCRIPTION_TYPE_APPLICATION}
+	stream, err := client.Subscribe(context.Background(), types)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Receive(); err != nil {
+		t.Fatalf("unexpected receive error: %v", err)
+	}
+	if stream.Reconnects() != 2 {
+		t.Fatalf("expected 2 reconnects, got %d", stream.Reconnects())
+	}
+	for _, got := range streaming.types {
+		if len(got) != 1 || got[0] != types[0] {
+			t.Fatalf("resubscribed with unexpected types: %v", got)
+		}
+	}
+}
+
+func TestStreamWrapper_ReconnectsBounded(t *testing.T) {
+	streaming := &testStreamingService{resets: maxStreamReconnects + 1}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewStreamingServiceHandler(streaming))
+	})
+	client.retryBackoff = time.Millisecond
+
+	stream, err := client.Subscribe(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Receive(); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Fatalf("expected unavailable error, got: %v", err)
+	}
+	if stream.Reconnects() != maxStreamReconnects {
+		t.Fatalf("expected %d reconnects, got %d", maxStreamReconnects, stream.Reconnects())
+	}
+}
+
+func TestGetApplicationResourceAccess_APIVersion(t *testing.T) {
+	for _, tt := range []struct {
+		apiVersion string
+		static     bool
+	}{
+		{"", true},
+		{"0.9.1", true},
+		{"0.8.2", false},
+	} {
+		gate := &testGateProxySSH{access: &aquariumv2.GateProxySSHAccess{}}
+		client := newTestFish(t, func(mux *http.ServeMux) {
+			mux.Handle(aquariumv2connect.NewGateProxySSHServiceHandler(gate))
+		})
+		if tt.apiVersion != "" {
+			client.apiVersion = version.Must(version.NewVersion(tt.apiVersion))
+		}
+
+		if _, err := client.GetApplicationResourceAccess(context.Background(), "resource-uid", true); err != nil {
+			t.Fatalf("api_version %q: unexpected error: %v", tt.apiVersion, err)
+		}
+		if (gate.request.Static != nil) != tt.static {
+			t.Errorf("api_version %q: expected static sent %v, got %v", tt.apiVersion, tt.static, gate.request.Static != nil)
+		}
+	}
+}
+
+func TestNewHTTPClient_UnixSocket(t *testing.T) {
+	// Socket path length is limited, so not using the long t.TempDir() path
+	dir, err := os.MkdirTemp("", "fish")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "fish.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unable to listen on unix socket: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewUserServiceHandler(&testUserService{}))
+	server := &http.Server{Handler: h2c.NewHandler(http.StripPrefix("/grpc", mux), &http2.Server{})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	config := &Config{Endpoint: "unix://" + socketPath}
+	httpClient, err := NewHTTPClient(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, protocol := range []string{ProtocolConnect, ProtocolGRPC, ProtocolGRPCWeb} {
+		client := NewAPIClient(APIEndpointURL(config.Endpoint, DefaultAPIPath), APIAuth{Username: "admin", Password: "admin"}, httpClient,
+			WithProtocol(protocol))
+		user, err := client.GetCurrentUser(context.Background())
+		if err != nil {
+			t.Fatalf("protocol %s: unexpected error: %v", protocol, err)
+		}
+		if user.GetName() != "admin" {
+			t.Errorf("protocol %s: expected user admin, got %q", protocol, user.GetName())
+		}
+	}
+}
+
+func TestNewHTTPClient_ConnectionPool(t *testing.T) {
+	var b Builder
+	raw := testConfig()
+	raw["max_conns_per_host"] = 8
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	httpClient, err := NewHTTPClient(&b.config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tr := httpClient.Transport.(*http.Transport)
+	if tr.MaxIdleConns != defaultMaxIdleConns || tr.MaxIdleConnsPerHost != defaultMaxIdleConns {
+		t.Errorf("expected %d idle connections, got %d (per host %d)", defaultMaxIdleConns, tr.MaxIdleConns, tr.MaxIdleConnsPerHost)
+	}
+	if tr.MaxConnsPerHost != 8 {
+		t.Errorf("expected 8 connections per host, got %d", tr.MaxConnsPerHost)
+	}
+	if tr.IdleConnTimeout != 5*time.Minute {
+		t.Errorf("expected 5m idle connection timeout, got %s", tr.IdleConnTimeout)
+	}
+	// HTTP/2 is configured by ConfigureTransports, which registers the h2 protocol upgrade
+	if _, ok := tr.TLSNextProto["h2"]; !ok {
+		t.Errorf("expected HTTP/2 to be configured for the transport")
+	}
+
+	raw["max_idle_conns"] = -1
+	b = Builder{}
+	if _, _, err := b.Prepare(raw); err == nil {
+		t.Fatalf("expected negative max_idle_conns error")
+	}
+}
+
+func TestNewConfiguredAPIClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewUserServiceHandler(&testUserService{}))
+	server := httptest.NewServer(http.StripPrefix("/grpc", mux))
+	t.Cleanup(server.Close)
+
+	// Not prepared config, like the one created by the data sources
+	config := &Config{
+		Endpoint:       server.URL,
+		APIPath:        DefaultAPIPath,
+		Username:       "admin",
+		Password:       "admin",
+		RequestTimeout: "5s",
+	}
+	client, err := NewConfiguredAPIClient(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.requestTimeout != 5*time.Second {
+		t.Errorf("expected 5s request timeout, got %s", client.requestTimeout)
+	}
+	user, err := client.GetCurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.GetName() != "admin" {
+		t.Errorf("expected user admin, got %q", user.GetName())
+	}
+
+	config.RequestTimeout = "five seconds"
+	if _, err := NewConfiguredAPIClient(config); err == nil {
+		t.Fatalf("expected invalid request_timeout error")
+	}
+}
+
+func TestNewConfiguredAPIClient_OAuth2OverTLS(t *testing.T) {
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"test-token","token_type":"bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tokenServer.Certificate().Raw})
+
+	// The API transports are dialing the socket or plain TCP, so the token is requested separately
+	for _, endpoint := range []string{"unix:///nonexistent/fish.sock", "http://127.0.0.1:1"} {
+		config := &Config{
+			Endpoint:               endpoint,
+			AllowInsecureTransport: strings.HasPrefix(endpoint, "http://"),
+			OAuthTokenURL:          tokenServer.URL + "/token",
+			OAuthClientID:          "client",
+			OAuthClientSecret:      "secret",
+			CACertPEM:              string(caPEM),
+		}
+		client, err := NewConfiguredAPIClient(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", endpoint, err)
+		}
+		if err := client.EnsureToken(); err != nil {
+			t.Fatalf("%s: unable to get the token over TLS: %v", endpoint, err)
+		}
+	}
+}
+
+func TestBuilderPrepare_ProxyWithDirectTransport(t *testing.T) {
+	for _, endpoint := range map[string]map[string]any{
+		"unix":     {"endpoint": "unix:///var/run/fish.sock"},
+		"insecure": {"endpoint": "http://localhost:8001", "allow_insecure_transport": true},
+	} {
+		raw := testConfig()
+		for k, v := range endpoint {
+			raw[k] = v
+		}
+		raw["proxy_url"] = "http://proxy.example.com:3128"
+
+		var b Builder
+		if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "proxy_url can't be used") {
+			t.Fatalf("%v: expected proxy_url error, got: %v", endpoint, err)
+		}
+	}
+}
+
+func TestAPIEndpointURL(t *testing.T) {
+	for _, tt := range []struct {
+		endpoint string
+		apiPath  string
+		expected string
+	}{
+		{"https://fish.example.com:8001", "grpc", "https://fish.example.com:8001/grpc"},
+		{"https://fish.example.com:8001/", "grpc", "https://fish.example.com:8001/grpc"},
+		{"https://fish.example.com", "/api/v2/grpc/", "https://fish.example.com/api/v2/grpc"},
+		{"https://fish.example.com", "api/v2/grpc", "https://fish.example.com/api/v2/grpc"},
+		{"https://fish.example.com/", "/", "https://fish.example.com"},
+		{"https://fish.example.com/custom/", "grpc", "https://fish.example.com/custom"},
+		{"unix:///run/fish.sock", "/api/grpc/", "http://localhost/api/grpc"},
+	} {
+		if actual := APIEndpointURL(tt.endpoint, tt.apiPath); actual != tt.expected {
+			t.Errorf("APIEndpointURL(%q, %q): expected %q, got %q", tt.endpoint, tt.apiPath, tt.expected, actual)
+		}
+	}
+}
@@ -14,11 +14,40 @@
 
 package aquarium
 
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+)
+
 // packersdk.Artifact implementation
 type Artifact struct {
+	// UID of the image created by TaskImage
+	ImageUID string
+	// Name of the image if it was returned by TaskImage
+	ImageName string
+	// Paths of the image files if they were returned by TaskImage
+	ImagePaths []string
+	// Label used to build the image
+	LabelName    string
+	LabelVersion int32
+	// Aquarium Fish endpoint where the image was built
+	Endpoint string
+	// Application and its resource used for the build, they are deallocated after the build unless
+	// deallocate_on_success is disabled
+	ApplicationUID string
+	ResourceUID    string
+	// Build was validated only, nothing was allocated or created
+	DryRun bool
+
 	// StateData should store data such as GeneratedData
 	// to be shared with post-processors
 	StateData map[string]any
+
+	client *APIClient
 }
 
 func (*Artifact) BuilderId() string {
@@ -26,21 +55,124 @@ func (*Artifact) BuilderId() string {
 }
 
 func (a *Artifact) Files() []string {
-	return []string{}
+	return a.ImagePaths
 }
 
-func (*Artifact) Id() string {
-	return ""
+func (a *Artifact) Id() string {
+	return a.ImageUID
 }
 
 func (a *Artifact) String() string {
-	return ""
+	if a.DryRun {
+		return fmt.Sprintf("Dry run: image would be built from label '%s' version %d on %s",
+			a.LabelName, a.LabelVersion, a.Endpoint)
+	}
+	if a.ImageUID == "" {
+		if a.ResourceUID != "" {
+			return fmt.Sprintf("No image was created, ephemeral resource %s of application %s was allocated from label '%s' version %d on %s",
+				a.ResourceUID, a.ApplicationUID, a.LabelName, a.LabelVersion, a.Endpoint)
+		}
+		return fmt.Sprintf("No image was created from label '%s' version %d on %s",
+			a.LabelName, a.LabelVersion, a.Endpoint)
+	}
+	name := a.ImageName
+	if name == "" {
+		name = a.ImageUID
+	}
+	return fmt.Sprintf("Image '%s' (UID: %s) created from label '%s' version %d on %s",
+		name, a.ImageUID, a.LabelName, a.LabelVersion, a.Endpoint)
 }
 
 func (a *Artifact) State(name string) any {
 	return a.StateData[name]
 }
 
+// Destroy deallocates the application if it was kept allocated. Fish has no API to remove the
+// image, so it's kept in the driver storage
 func (a *Artifact) Destroy() error {
+	if a.DryRun {
+		return nil
+	}
+	if a.ImageUID != "" {
+		log.Printf("[WARN] Image %s is not removed, Aquarium Fish has no API for it", a.ImageUID)
+	}
+	if a.ApplicationUID == "" {
+		return nil
+	}
+	client, err := a.apiClient()
+	if err != nil {
+		return fmt.Errorf("unable to connect to AquariumFish to destroy the artifact: %v", err)
+	}
+
+	return a.deallocate(context.Background(), client)
+}
+
+// deallocate releases the application resource unless it's already deallocated
+func (a *Artifact) deallocate(ctx context.Context, client *APIClient) error {
+	appState, err := client.GetApplicationState(ctx, a.ApplicationUID)
+	if err != nil {
+		return fmt.Errorf("unable to get application %s state: %v", a.ApplicationUID, err)
+	}
+	switch appState.GetStatus() {
+	case aquariumv2.ApplicationState_DEALLOCATE, aquariumv2.ApplicationState_DEALLOCATED:
+		return nil
+	}
+	if err := client.DeallocateApplication(ctx, a.ApplicationUID); err != nil {
+		return fmt.Errorf("unable to deallocate application %s: %v", a.ApplicationUID, err)
+	}
+	log.Printf("[INFO] Application %s deallocate request sent", a.ApplicationUID)
 	return nil
 }
+
+// artifactConnectionKey is the StateData key of the connection details used by Destroy
+const artifactConnectionKey = "aquarium_connection"
+
+// newArtifactConnection returns the connection details to store in the artifact, the password and
+// token are not included since StateData is available to all the post-processors
+func newArtifactConnection(c *Config) map[string]string {
+	return map[string]string{
+		"endpoint":                 c.Endpoint,
+		"api_path":                 c.APIPath,
+		"protocol":                 c.Protocol,
+		"api_version":              c.APIVersion,
+		"username":                 c.Username,
+		"user_agent":               c.UserAgent,
+		"request_timeout":          c.RequestTimeout,
+		"ca_cert_file":             c.CACertFile,
+		"ca_cert_pem":              c.CACertPEM,
+		"insecure_skip_tls_verify": strconv.FormatBool(c.InsecureSkipTLSVerify),
+		"allow_insecure_transport": strconv.FormatBool(c.AllowInsecureTransport),
+		"proxy_url":                c.ProxyURL,
+	}
+}
+
+// apiClient returns the build API client or reconnects with the details stored in StateData, the
+// secrets are taken from the environment or netrc in this case
+func (a *Artifact) apiClient() (*APIClient, error) {
+	if a.client != nil {
+		return a.client, nil
+	}
+	connection, ok := a.StateData[artifactConnectionKey].(map[string]string)
+	if !ok {
+		return nil, fmt.Errorf("no connection details in the artifact")
+	}
+	config := &Config{
+		Endpoint:               connection["endpoint"],
+		APIPath:                connection["api_path"],
+		Protocol:               connection["protocol"],
+		APIVersion:             connection["api_version"],
+		Username:               connection["username"],
+		UserAgent:              connection["user_agent"],
+		RequestTimeout:         connection["request_timeout"],
+		CACertFile:             connection["ca_cert_file"],
+		CACertPEM:              connection["ca_cert_pem"],
+		InsecureSkipTLSVerify:  connection["insecure_skip_tls_verify"] == "true",
+		AllowInsecureTransport: connection["allow_insecure_transport"] == "true",
+		ProxyURL:               connection["proxy_url"],
+	}
+	config.loadEnv()
+	if err := config.loadNetrc(); err != nil {
+		return nil, err
+	}
+	return NewConfiguredAPIClient(config)
+}
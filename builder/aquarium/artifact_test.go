@@ -0,0 +1,90 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+)
+
+func TestArtifactDestroy_Deallocates(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      aquariumv2.ApplicationState_Status
+		deallocated int
+	}{
+		{"kept allocated", aquariumv2.ApplicationState_ALLOCATED, 1},
+		{"already deallocated", aquariumv2.ApplicationState_DEALLOCATED, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &testApplicationService{statuses: []aquariumv2.ApplicationState_Status{tt.status}}
+			client := newTestFish(t, func(mux *http.ServeMux) {
+				mux.Handle(aquariumv2connect.NewApplicationServiceHandler(service))
+			})
+
+			artifact := &Artifact{ApplicationUID: "app-uid", client: client}
+			if err := artifact.Destroy(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(service.deallocated) != tt.deallocated {
+				t.Fatalf("expected %d deallocations, got: %v", tt.deallocated, service.deallocated)
+			}
+		})
+	}
+}
+
+// The endpoint certificate is signed by the custom CA, so the reconnect needs the build TLS settings
+func TestArtifactDestroy_Reconnects(t *testing.T) {
+	service := &testApplicationService{statuses: []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_ALLOCATED}}
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewApplicationServiceHandler(service))
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	t.Setenv("AQUARIUM_PASSWORD", "admin")
+
+	artifact := &Artifact{
+		ApplicationUID: "app-uid",
+		StateData: map[string]any{artifactConnectionKey: newArtifactConnection(&Config{
+			Endpoint:  server.URL + "/",
+			Protocol:  ProtocolConnect,
+			Username:  "admin",
+			CACertPEM: string(caPEM),
+		})},
+	}
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(service.deallocated) != 1 {
+		t.Fatalf("application was not deallocated: %v", service.deallocated)
+	}
+
+	if err := (&Artifact{ApplicationUID: "app-uid"}).Destroy(); err == nil {
+		t.Fatalf("expected error without connection details")
+	}
+}
+
+// Fish can't remove the image, so it shouldn't fail the post-processors chain
+func TestArtifactDestroy_KeepsImage(t *testing.T) {
+	if err := (&Artifact{ImageUID: "image-uid"}).Destroy(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
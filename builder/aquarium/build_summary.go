@@ -0,0 +1,106 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// BuildSummary is the machine-readable build outcome written to build_summary_path
+type BuildSummary struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+
+	BuildID        string `json:"build_id"`
+	Endpoint       string `json:"endpoint"`
+	LabelName      string `json:"label_name,omitempty"`
+	LabelVersion   int32  `json:"label_version,omitempty"`
+	ApplicationUID string `json:"application_uid,omitempty"`
+	ResourceUID    string `json:"resource_uid,omitempty"`
+	SSHHost        string `json:"ssh_host,omitempty"`
+	SSHPort        int    `json:"ssh_port,omitempty"`
+	ImageUID       string `json:"image_uid,omitempty"`
+	ImageName      string `json:"image_name,omitempty"`
+	ImagePath      string `json:"image_path,omitempty"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Durations in seconds, allocation one is set when the application was allocated
+	DurationSeconds           float64 `json:"duration_seconds"`
+	AllocationDurationSeconds float64 `json:"allocation_duration_seconds,omitempty"`
+}
+
+// newBuildSummary collects the build outcome from the state
+func newBuildSummary(config *Config, state multistep.StateBag, startedAt time.Time) *BuildSummary {
+	summary := &BuildSummary{
+		Success:         true,
+		BuildID:         config.buildID,
+		Endpoint:        config.Endpoint,
+		StartedAt:       startedAt,
+		FinishedAt:      time.Now(),
+		DurationSeconds: time.Since(startedAt).Seconds(),
+	}
+	if err, ok := state.GetOk("error"); ok {
+		summary.Success = false
+		summary.Error = err.(error).Error()
+	}
+	if label, ok := state.GetOk("selected_label"); ok {
+		summary.LabelName = label.(*aquariumv2.Label).GetName()
+		summary.LabelVersion = label.(*aquariumv2.Label).GetVersion()
+	}
+	if app, ok := state.GetOk("application"); ok {
+		summary.ApplicationUID = app.(*aquariumv2.Application).GetUid()
+	}
+	if resource, ok := state.GetOk("application_resource"); ok {
+		summary.ResourceUID = resource.(*aquariumv2.ApplicationResource).GetUid()
+	}
+	if sshHost, ok := state.GetOk("ssh_host"); ok {
+		summary.SSHHost = sshHost.(string)
+	}
+	if generatedData, ok := state.Get("generated_data").(map[string]any); ok {
+		if port, ok := generatedData["SSHPort"].(string); ok {
+			summary.SSHPort, _ = strconv.Atoi(port)
+		}
+	}
+	if result, ok := state.GetOk("image_result"); ok {
+		image := result.(*ImageResult)
+		summary.ImageUID = image.UID
+		summary.ImageName = image.Name
+		summary.ImagePath = image.Path
+	}
+	if duration, ok := state.GetOk("allocation_duration"); ok {
+		summary.AllocationDurationSeconds = duration.(time.Duration).Seconds()
+	}
+	return summary
+}
+
+// writeBuildSummary stores the summary as JSON file
+func writeBuildSummary(path string, summary *BuildSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal build summary: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write build summary to %s: %v", path, err)
+	}
+	return nil
+}
@@ -13,17 +13,27 @@
 // Author: Sergei Parshev (@sparshev)
 
 //go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc struct-markdown
 
 package aquarium
 
 import (
 	"context"
-	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
@@ -31,6 +41,9 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	"golang.org/x/crypto/ssh"
 )
 
 const BuilderId = "aquarium.builder"
@@ -38,51 +51,304 @@ const BuilderId = "aquarium.builder"
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	// AquariumFish API connection settings
-	Endpoint              string `mapstructure:"endpoint" required:"true"`
-	Username              string `mapstructure:"username" required:"true"`
-	Password              string `mapstructure:"password" required:"true"`
-	InsecureSkipTLSVerify bool   `mapstructure:"insecure_skip_tls_verify"`
+	// AquariumFish API endpoint, like https://fish.example.com:8001/ or unix:///path/to/sock for
+	// the local Fish. AQUARIUM_ENDPOINT environment variable is used if not set
+	Endpoint string `mapstructure:"endpoint" required:"true"`
+	// API user, AQUARIUM_USERNAME environment variable or the netrc entry of the endpoint host is
+	// used if not set
+	Username string `mapstructure:"username"`
+	// API password, AQUARIUM_PASSWORD environment variable or the netrc entry of the endpoint host
+	// is used if not set
+	Password string `mapstructure:"password"`
+	// Skips the endpoint certificate verification
+	InsecureSkipTLSVerify bool `mapstructure:"insecure_skip_tls_verify"`
 
-	// Label specification
-	LabelName    string `mapstructure:"label_name" required:"true"`
+	// Path to the custom CA bundle to verify the endpoint certificate
+	CACertFile string `mapstructure:"ca_cert_file"`
+	// Inline PEM custom CA bundle to verify the endpoint certificate
+	CACertPEM string `mapstructure:"ca_cert_pem"`
+
+	// Allows plaintext HTTP/2 (h2c) for http:// endpoint, only for local development and testing
+	AllowInsecureTransport bool `mapstructure:"allow_insecure_transport"`
+
+	// Proxy to reach the endpoint and the OAuth2 token URL, overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	// environment. Can't be used with unix:// endpoint and allow_insecure_transport
+	ProxyURL string `mapstructure:"proxy_url"`
+
+	// Maximum number of the idle connections kept to the endpoint, tune it for many builds running
+	// against the same endpoint from one host. Defaults to 100
+	MaxIdleConns int `mapstructure:"max_idle_conns"`
+	// Maximum number of the connections to the endpoint, 0 means no limit
+	MaxConnsPerHost int `mapstructure:"max_conns_per_host"`
+	// Time the idle connection is kept open. Defaults to 5m
+	IdleConnTimeout string `mapstructure:"idle_conn_timeout"`
+
+	// Path of the RPC services used when endpoint has no path, "grpc" by default and "/" for the root
+	APIPath string `mapstructure:"api_path"`
+
+	// RPC wire protocol: connect (default), grpc or grpc-web
+	Protocol string `mapstructure:"protocol"`
+
+	// Version of the Fish server API, like "0.8.2", when it's older than the plugin one the newer
+	// optional request fields are not sent
+	APIVersion string `mapstructure:"api_version"`
+
+	// Overrides the default "packer-plugin-aquarium/<version> packer/<version>" User-Agent
+	UserAgent string `mapstructure:"user_agent"`
+
+	// JSON file with endpoint, username and password to use when they are not set in config
+	CredentialsFile string `mapstructure:"credentials_file"`
+
+	// Bearer token, when set it takes precedence over username/password
+	Token string `mapstructure:"token"`
+
+	// AWS Secrets Manager secret with JSON username/password or token to use when they are not set
+	// in config
+	AWSSecretID string `mapstructure:"aws_secret_id"`
+	// Region of aws_secret_id, it's taken from AWS environment if not set
+	AWSSecretRegion string `mapstructure:"aws_secret_region"`
+
+	// Token URL of the OAuth2 client-credentials grant to receive the bearer token dynamically
+	OAuthTokenURL string `mapstructure:"oauth_token_url"`
+	// Client ID of the OAuth2 client-credentials grant
+	OAuthClientID string `mapstructure:"oauth_client_id"`
+	// Client secret of the OAuth2 client-credentials grant
+	OAuthClientSecret string `mapstructure:"oauth_client_secret"`
+	// Scopes requested with the OAuth2 token
+	OAuthScopes []string `mapstructure:"oauth_scopes"`
+
+	// Name of the label to build the image from
+	LabelName string `mapstructure:"label_name"`
+	// Version of the label_name label, "last" or not set selects the latest one
 	LabelVersion string `mapstructure:"label_version"`
+	// UID of the exact label, the name lookup is skipped for it
+	LabelUID string `mapstructure:"label_uid"`
+	// Semver constraint (ex. ">=3, <5") to select the latest matching label version
+	LabelVersionConstraint string `mapstructure:"label_version_constraint"`
+	// Allows latest version selection to pick the labels marked as prerelease in metadata
+	LabelIncludePrerelease bool `mapstructure:"label_include_prerelease"`
+	// Index of the label definition the allocated resource has to use. Fish picks the definition by
+	// itself and Application has no field for it, so the index is only checked against the label
+	// definitions before the application creation and the build fails if Fish allocates another
+	// one. Not set accepts any definition
+	DefinitionIndex *int `mapstructure:"definition_index"`
+	// Local YAML or JSON label definition, the label is created in Fish before the build and used
+	// for it. Version 0 or not set means the next one after the existing versions of the label
+	LabelDefinitionFile string `mapstructure:"label_definition_file"`
+	// Removes the label created from label_definition_file after the build
+	CleanupLabel bool `mapstructure:"cleanup_label"`
+	// Continues the interrupted build with the existing ALLOCATED application instead of creating a
+	// new one, the label options are ignored since the application label is used
+	ResumeApplicationUID string `mapstructure:"resume_application_uid"`
 
-	// Timeout and retry settings
+	// Timeout of each of connection_retries API connectivity checks, the label lookup and the logs
+	// dump, it doesn't cover the SSH connection (ssh_timeout) or the allocation wait. Defaults to 10m
 	ConnectionTimeout string `mapstructure:"connection_timeout"`
-	ConnectionRetries int    `mapstructure:"connection_retries"`
+	// Number of the API connectivity checks before the build. Defaults to 60
+	ConnectionRetries int `mapstructure:"connection_retries"`
+	// Number of attempts of each idempotent API request failed with a transient error. Defaults to 5
+	RequestRetries int `mapstructure:"request_retries"`
+	// Initial delay between the retries, it's doubled for each of them. Defaults to 1s
+	RetryBackoff string `mapstructure:"retry_backoff"`
+	// Timeout of each API request attempt. Defaults to 1m
+	RequestTimeout string `mapstructure:"request_timeout"`
+	// Time for Fish to allocate the resource after the application is created, counted separately
+	// for each of allocation_retries. Defaults to 30m
 	AllocationTimeout string `mapstructure:"allocation_timeout"`
+	// Time for Fish to create the image. Defaults to 30m
+	ImageTimeout string `mapstructure:"image_timeout"`
+	// Interval of the image task status checks. Defaults to 15s
+	ImagePollInterval string `mapstructure:"image_poll_interval"`
+	// Deadline of the application creation request including its retries, nothing is allocated yet
+	// so it's better to fail early than to wait for the build timeout. Defaults to 60s
+	CreateApplicationTimeout string `mapstructure:"create_application_timeout"`
+	// Recreates the application this many times when its allocation fails
+	AllocationRetries int `mapstructure:"allocation_retries"`
+	// Time to wait for the application deallocation. Defaults to 2m
+	DeallocationTimeout string `mapstructure:"deallocation_timeout"`
+	// Interval of the application state checks during deallocation. Defaults to 10s
+	DeallocationPollInterval string `mapstructure:"deallocation_poll_interval"`
+	// Waits for the application to be deallocated after the build, enabled by default
+	WaitForDeallocation config.Trilean `mapstructure:"wait_for_deallocation"`
+	// Deallocates this builder applications older than the duration before the build, disabled if empty
+	CleanupOrphansOlderThan string `mapstructure:"cleanup_orphans_older_than"`
+	// Bounds the whole build from connection to the image creation, the cleanup still runs after it
+	// is reached. Disabled if empty
+	BuildTimeout string `mapstructure:"build_timeout"`
+
+	// Name of the produced image
+	ImageName string `mapstructure:"image_name"`
+	// Additional TaskImage options passed to Fish
+	ImageOptions map[string]any `mapstructure:"image_options"`
+	// Custom metadata (team, source commit, expiry) stored by Fish on the image, it's passed as the
+	// image_metadata TaskImage option and converted the same way as application_metadata
+	ImageMetadata map[string]any `mapstructure:"image_metadata"`
+
+	// Skips the cluster nodes capacity check done before the application creation
+	SkipCapacityCheck bool `mapstructure:"skip_capacity_check"`
 
-	// Additional metadata to pass to the application
+	// Validates the config, credentials, label and capacity without creating the application
+	DryRun bool `mapstructure:"dry_run"`
+
+	// Skips the image creation, so the resource is only allocated, provisioned and deallocated
+	SkipCreateImage bool `mapstructure:"skip_create_image"`
+
+	// Application tasks executed one by one after provisioning and before the image creation
+	Tasks []TaskConfig `mapstructure:"tasks"`
+
+	// Owner of the application, Fish uses the authenticated user if empty and requires the
+	// permission to create the application on behalf of another user
+	Owner string `mapstructure:"owner"`
+
+	// Label CPU override, Fish API doesn't support per-application requirements yet so setting it
+	// fails the validation instead of being silently ignored
+	ResourceCPU int `mapstructure:"resource_cpu"`
+	// Label RAM override, not supported by Fish API the same way as resource_cpu
+	ResourceRAM int `mapstructure:"resource_ram"`
+	// Label disk override, not supported by Fish API the same way as resource_cpu
+	ResourceDisk string `mapstructure:"resource_disk"`
+
+	// Additional metadata to pass to the application. HCL delivers the values as strings, so they
+	// are converted: "true" and "false" to bool, numbers without leading zeros and exponent (up to 15
+	// integer digits) to number and valid JSON arrays or objects to the decoded values
 	ApplicationMetadata map[string]any `mapstructure:"application_metadata"`
+	// Disables the application_metadata values conversion, so all the strings are passed as is
+	MetadataRawStrings bool `mapstructure:"metadata_raw_strings"`
+	// JSON file with the application metadata, inline application_metadata keys override it
+	ApplicationMetadataFile string `mapstructure:"application_metadata_file"`
+	// Prefix of the metadata keys set by the builder (build, build_id, etc), default is "packer_".
+	// The application_metadata keys take precedence over the builder ones
+	MetadataKeyPrefix string `mapstructure:"metadata_key_prefix"`
+
+	// Requests one-time SSH credentials instead of the static ones, OTP is valid only for a single
+	// connection so the provisioners can't reconnect after the connection is lost
+	SSHUseOTP bool `mapstructure:"ssh_use_otp"`
+
+	// Path to write the SSH private key received from Fish, it's kept after the build to reconnect
+	SSHKeyPath string `mapstructure:"ssh_key_path"`
+
+	// Fingerprint of the ProxySSH host key to verify, like "SHA256:..." or the legacy MD5 one. Fish
+	// doesn't provide the host key, so any key is accepted with a warning when it's not set
+	SSHHostKeyFingerprint string `mapstructure:"ssh_host_key_fingerprint"`
+	// Explicitly accepts any SSH host key without the warning, the connection could be intercepted so
+	// use it only in trusted networks
+	SSHInsecureHostKey bool `mapstructure:"ssh_insecure_host_key"`
+
+	// Forwards the local SSH agent (SSH_AUTH_SOCK) to the resource through ProxySSH, disabled by default
+	// because anyone with root on the resource could use the agent keys while the build is running
+	SSHForwardAgent bool `mapstructure:"ssh_forward_agent"`
+
+	// Prints the SSH password and connection hint, which is also enabled in packer debug mode
+	ExposeSSHCredentials bool `mapstructure:"expose_ssh_credentials"`
+
+	// Path to write the JSON summary of the build outcome to, disabled when empty
+	BuildSummaryPath string `mapstructure:"build_summary_path"`
+
+	// Path to write the resource, application state and tasks details to when the build fails, Fish
+	// has no API for the resource logs. Disabled when empty
+	FailureLogPath string `mapstructure:"failure_log_path"`
+
+	// Path to append the provisioners output to for the audit, disabled when empty
+	ProvisionLogPath string `mapstructure:"provision_log_path"`
 
-	// SSH communication settings
+	// Skips the application deallocation when the build fails to allow debugging of the resource
+	KeepResourceOnError bool `mapstructure:"keep_resource_on_error"`
+	// Deallocates the application after the successful build, enabled by default. When disabled the
+	// resource is left running for the follow-up processes which should deallocate it
+	DeallocateOnSuccess config.Trilean `mapstructure:"deallocate_on_success"`
+
+	// SSH communication settings, host/port and credentials are received from Fish ProxySSH. Bastion
+	// settings (ssh_bastion_host, ssh_bastion_username, etc.) are kept as is to reach the ProxySSH
 	Communicator communicator.Config `mapstructure:",squash"`
 
-	// Deprecated field for backward compatibility
-	MockOption string `mapstructure:"mock"`
+	// Deprecated field for backward compatibility, it's ignored and will be removed
+	MockOption string `mapstructure:"mock" undocumented:"true"`
+
+	ctx interpolate.Context
+
+	// Unique identifier of the build stored in the application metadata
+	buildID string
+	// Parsed label_definition_file
+	labelDefinition *aquariumv2.Label
+	// Parsed api_version
+	apiVersion *version.Version
 
 	// Parsed timeout values
-	connectionTimeoutDuration time.Duration
-	allocationTimeoutDuration time.Duration
+	idleConnTimeoutDuration          time.Duration
+	connectionTimeoutDuration        time.Duration
+	retryBackoffDuration             time.Duration
+	requestTimeoutDuration           time.Duration
+	allocationTimeoutDuration        time.Duration
+	createApplicationTimeoutDuration time.Duration
+	imageTimeoutDuration             time.Duration
+	imagePollIntervalDuration        time.Duration
+	deallocationTimeoutDuration      time.Duration
+	deallocationPollIntervalDuration time.Duration
+	cleanupOrphansOlderThanDuration  time.Duration
+	buildTimeoutDuration             time.Duration
+}
+
+// TaskConfig describes the Fish application task to execute
+type TaskConfig struct {
+	// Name of the Fish task, like TaskSnapshot
+	Task string `mapstructure:"task" required:"true"`
+	// Application state when the task is executed, ALLOCATED by default to run it during the build.
+	// Fish runs the DEALLOCATE tasks only when the application is deallocated after the build, so
+	// the builder doesn't wait for their results
+	When string `mapstructure:"when"`
+	// Options passed to the task
+	Options map[string]any `mapstructure:"options"`
+
+	when aquariumv2.ApplicationState_Status
 }
 
 type Builder struct {
 	config Config
 	runner multistep.Runner
+	// Receives the steps telemetry, selected by AQUARIUM_METRICS when not set
+	metrics Metrics
 }
 
 func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
 
 func (b *Builder) Prepare(raws ...any) (generatedVars []string, warnings []string, err error) {
+	// All the string fields including the squashed communicator ones are interpolated here, before
+	// any of them is used. Vault secrets are resolved by Packer core (vault function in HCL2 or in
+	// the JSON variables section) and get here as plain values or user variables
 	err = config.Decode(&b.config, &config.DecodeOpts{
-		PluginType:  "packer.builder.aquarium",
-		Interpolate: true,
+		PluginType:         "packer.builder.aquarium",
+		Interpolate:        true,
+		InterpolateContext: &b.config.ctx,
 	}, raws...)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Resolve the connection settings not set in config in order: AWS secret, credentials file,
+	// environment and netrc entry for the endpoint host
+	if b.config.AWSSecretID != "" {
+		if err := b.config.loadAWSSecret(); err != nil {
+			return nil, nil, err
+		}
+	} else if b.config.AWSSecretRegion != "" {
+		return nil, nil, fmt.Errorf("aws_secret_region can't be used without aws_secret_id")
+	}
+	if b.config.CredentialsFile != "" {
+		found, err := b.config.loadCredentialsFile()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("credentials_file %q not found, skipping it", b.config.CredentialsFile))
+		}
+	}
+	if fromEnv := b.config.loadEnv(); len(fromEnv) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Using values from environment: %s", strings.Join(fromEnv, ", ")))
+	}
+	if err := b.config.loadNetrc(); err != nil {
+		return nil, nil, err
+	}
+
 	// Set default values
 	if b.config.ConnectionTimeout == "" {
 		b.config.ConnectionTimeout = "10m"
@@ -90,59 +356,388 @@ func (b *Builder) Prepare(raws ...any) (generatedVars []string, warnings []strin
 	if b.config.ConnectionRetries <= 0 {
 		b.config.ConnectionRetries = 60
 	}
+	if b.config.RequestRetries <= 0 {
+		b.config.RequestRetries = 5
+	}
+	if b.config.Protocol == "" {
+		b.config.Protocol = ProtocolConnect
+	}
+	if b.config.APIPath == "" {
+		b.config.APIPath = DefaultAPIPath
+	}
+	if b.config.RetryBackoff == "" {
+		b.config.RetryBackoff = "1s"
+	}
+	if b.config.MaxIdleConns == 0 {
+		b.config.MaxIdleConns = defaultMaxIdleConns
+	}
+	if b.config.IdleConnTimeout == "" {
+		b.config.IdleConnTimeout = "5m"
+	}
+	if b.config.RequestTimeout == "" {
+		b.config.RequestTimeout = "1m"
+	}
 	if b.config.AllocationTimeout == "" {
 		b.config.AllocationTimeout = "30m"
 	}
+	if b.config.CreateApplicationTimeout == "" {
+		b.config.CreateApplicationTimeout = "60s"
+	}
+	if b.config.MetadataKeyPrefix == "" {
+		b.config.MetadataKeyPrefix = "packer_"
+	}
+	if b.config.ImageTimeout == "" {
+		b.config.ImageTimeout = "30m"
+	}
+	if b.config.ImagePollInterval == "" {
+		b.config.ImagePollInterval = "15s"
+	}
+	if b.config.DeallocationTimeout == "" {
+		b.config.DeallocationTimeout = "2m"
+	}
+	if b.config.DeallocationPollInterval == "" {
+		b.config.DeallocationPollInterval = "10s"
+	}
+
+	if b.config.MaxIdleConns < 0 {
+		return nil, nil, fmt.Errorf("max_idle_conns should not be negative")
+	}
+	if b.config.MaxConnsPerHost < 0 {
+		return nil, nil, fmt.Errorf("max_conns_per_host should not be negative")
+	}
 
 	// Parse timeout durations
+	b.config.idleConnTimeoutDuration, err = time.ParseDuration(b.config.IdleConnTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid idle_conn_timeout: %v", err)
+	}
+
 	b.config.connectionTimeoutDuration, err = time.ParseDuration(b.config.ConnectionTimeout)
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid connection_timeout: %v", err)
 	}
 
+	b.config.retryBackoffDuration, err = time.ParseDuration(b.config.RetryBackoff)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid retry_backoff: %v", err)
+	}
+
+	b.config.requestTimeoutDuration, err = time.ParseDuration(b.config.RequestTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid request_timeout: %v", err)
+	}
+
 	b.config.allocationTimeoutDuration, err = time.ParseDuration(b.config.AllocationTimeout)
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid allocation_timeout: %v", err)
 	}
 
+	b.config.createApplicationTimeoutDuration, err = time.ParseDuration(b.config.CreateApplicationTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid create_application_timeout: %v", err)
+	}
+	if b.config.createApplicationTimeoutDuration <= 0 {
+		return nil, nil, fmt.Errorf("create_application_timeout should be positive")
+	}
+
+	b.config.imageTimeoutDuration, err = time.ParseDuration(b.config.ImageTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid image_timeout: %v", err)
+	}
+
+	b.config.imagePollIntervalDuration, err = time.ParseDuration(b.config.ImagePollInterval)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid image_poll_interval: %v", err)
+	}
+	if b.config.imagePollIntervalDuration <= 0 {
+		return nil, nil, fmt.Errorf("image_poll_interval should be positive")
+	}
+
+	b.config.deallocationTimeoutDuration, err = time.ParseDuration(b.config.DeallocationTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid deallocation_timeout: %v", err)
+	}
+
+	b.config.deallocationPollIntervalDuration, err = time.ParseDuration(b.config.DeallocationPollInterval)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid deallocation_poll_interval: %v", err)
+	}
+	if b.config.deallocationPollIntervalDuration <= 0 {
+		return nil, nil, fmt.Errorf("deallocation_poll_interval should be positive")
+	}
+
+	if b.config.CleanupOrphansOlderThan != "" {
+		b.config.cleanupOrphansOlderThanDuration, err = time.ParseDuration(b.config.CleanupOrphansOlderThan)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cleanup_orphans_older_than: %v", err)
+		}
+		if b.config.cleanupOrphansOlderThanDuration <= 0 {
+			return nil, nil, fmt.Errorf("cleanup_orphans_older_than should be positive")
+		}
+	}
+
+	if b.config.BuildTimeout != "" {
+		b.config.buildTimeoutDuration, err = time.ParseDuration(b.config.BuildTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid build_timeout: %v", err)
+		}
+		if b.config.buildTimeoutDuration <= 0 {
+			return nil, nil, fmt.Errorf("build_timeout should be positive")
+		}
+		// The build is stopped by build_timeout before these waits are able to fail with their reason
+		if b.config.allocationTimeoutDuration > b.config.buildTimeoutDuration {
+			warnings = append(warnings, fmt.Sprintf("allocation_timeout %s exceeds build_timeout %s, the build could "+
+				"be stopped while waiting for the allocation", b.config.AllocationTimeout, b.config.BuildTimeout))
+		}
+		if b.config.connectionTimeoutDuration > b.config.buildTimeoutDuration {
+			warnings = append(warnings, fmt.Sprintf("connection_timeout %s exceeds build_timeout %s, the build could "+
+				"be stopped while connecting to the API", b.config.ConnectionTimeout, b.config.BuildTimeout))
+		}
+	}
+
 	// Validate required fields
 	if _, err := url.Parse(b.config.Endpoint); b.config.Endpoint == "" || err != nil {
 		return nil, nil, fmt.Errorf("aquarium endpoint is incorrect: %v", err)
 	}
-	if b.config.Username == "" {
-		return nil, nil, fmt.Errorf("aquarium username is required")
+	if strings.ContainsAny(b.config.APIPath, "?#") {
+		return nil, nil, fmt.Errorf("api_path %q should contain only the path", b.config.APIPath)
+	}
+	if socketPath, ok := unixSocketPath(b.config.Endpoint); ok && socketPath == "" {
+		return nil, nil, fmt.Errorf("aquarium endpoint should contain the socket path like unix:///path/to/sock")
+	}
+	if b.config.OAuthTokenURL != "" {
+		if _, err := url.Parse(b.config.OAuthTokenURL); err != nil {
+			return nil, nil, fmt.Errorf("oauth_token_url is incorrect: %v", err)
+		}
+		if b.config.OAuthClientID == "" || b.config.OAuthClientSecret == "" {
+			return nil, nil, fmt.Errorf("oauth_client_id and oauth_client_secret are required when oauth_token_url is set")
+		}
+	}
+	if b.config.Token == "" && b.config.OAuthTokenURL == "" {
+		if b.config.Username == "" {
+			return nil, nil, fmt.Errorf("aquarium username is required when token or oauth_token_url is not set")
+		}
+		if b.config.Password == "" {
+			return nil, nil, fmt.Errorf("aquarium password is required when token or oauth_token_url is not set")
+		}
+	}
+	if b.config.MockOption != "" {
+		// Newer auth configs were never supported by the mock, so most probably it's a leftover
+		if b.config.Token != "" || b.config.OAuthTokenURL != "" || b.config.CredentialsFile != "" || b.config.AWSSecretID != "" {
+			return nil, nil, fmt.Errorf("mock can't be used together with token, oauth_token_url, credentials_file or aws_secret_id, remove mock")
+		}
+		warnings = append(warnings, "mock is deprecated and ignored, it will be removed in the next major release: "+
+			"remove it and point endpoint to a test Aquarium Fish instance instead")
+	}
+	if b.config.LabelDefinitionFile != "" {
+		if b.config.LabelUID != "" || b.config.LabelVersion != "" || b.config.LabelVersionConstraint != "" {
+			return nil, nil, fmt.Errorf("label_definition_file can't be used together with label_uid, label_version or label_version_constraint")
+		}
+		if err := b.config.loadLabelDefinitionFile(); err != nil {
+			return nil, nil, err
+		}
+	} else if b.config.CleanupLabel {
+		return nil, nil, fmt.Errorf("cleanup_label can be used only with label_definition_file")
+	}
+	if b.config.ResumeApplicationUID != "" {
+		if b.config.DryRun || b.config.LabelDefinitionFile != "" {
+			return nil, nil, fmt.Errorf("resume_application_uid can't be used with dry_run or label_definition_file")
+		}
+	} else if b.config.LabelName == "" && b.config.LabelUID == "" {
+		return nil, nil, fmt.Errorf("label_name, label_uid, label_definition_file or resume_application_uid is required")
+	}
+	// The interpolated value is checked here to not fail mid-build
+	if b.config.LabelVersion != "" && b.config.LabelVersion != labelVersionLast {
+		if v, err := strconv.Atoi(b.config.LabelVersion); err != nil || v <= 0 {
+			return nil, nil, fmt.Errorf("label_version %q should be a positive number or \"last\"", b.config.LabelVersion)
+		}
+	}
+	if b.config.LabelVersionConstraint != "" {
+		if b.config.LabelVersion != "" {
+			return nil, nil, fmt.Errorf("label_version and label_version_constraint can't be used together")
+		}
+		if _, err := version.NewConstraint(b.config.LabelVersionConstraint); err != nil {
+			return nil, nil, fmt.Errorf("invalid label_version_constraint: %v", err)
+		}
+	}
+	if b.config.ResourceCPU != 0 || b.config.ResourceRAM != 0 || b.config.ResourceDisk != "" {
+		return nil, nil, fmt.Errorf("resource_cpu, resource_ram and resource_disk are not supported: " +
+			"Aquarium Fish application can't override the label resources, create a new label version instead")
+	}
+	if strings.TrimSpace(b.config.Owner) != b.config.Owner {
+		return nil, nil, fmt.Errorf("owner can't contain leading or trailing spaces")
+	}
+	if b.config.APIVersion != "" {
+		if b.config.apiVersion, err = version.NewVersion(b.config.APIVersion); err != nil {
+			return nil, nil, fmt.Errorf("invalid api_version: %v", err)
+		}
+	}
+	if b.config.AllocationRetries < 0 {
+		return nil, nil, fmt.Errorf("allocation_retries can't be negative")
+	}
+	if b.config.DefinitionIndex != nil && *b.config.DefinitionIndex < 0 {
+		return nil, nil, fmt.Errorf("definition_index can't be negative")
+	}
+	if b.config.ApplicationMetadataFile != "" {
+		if err := b.config.loadApplicationMetadataFile(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := b.config.prepareMetadata("application_metadata", b.config.ApplicationMetadata); err != nil {
+		return nil, nil, err
+	}
+	if err := b.config.prepareMetadata("image_metadata", b.config.ImageMetadata); err != nil {
+		return nil, nil, err
+	}
+	if _, collisions := mergeMetadata(b.config.ApplicationMetadata, b.config.builderMetadata()); len(collisions) > 0 {
+		warnings = append(warnings, fmt.Sprintf("application_metadata overrides the builder metadata keys %s, "+
+			"change metadata_key_prefix to keep both", strings.Join(collisions, ", ")))
 	}
-	if b.config.Password == "" {
-		return nil, nil, fmt.Errorf("aquarium password is required")
+	if _, err := newStruct(b.config.ImageOptions); err != nil {
+		return nil, nil, fmt.Errorf("invalid image_options: %v", err)
 	}
-	if b.config.LabelName == "" {
-		return nil, nil, fmt.Errorf("label_name is required")
+	taskNames := make(map[string]bool, len(b.config.Tasks))
+	for i := range b.config.Tasks {
+		task := &b.config.Tasks[i]
+		switch {
+		case task.Task == "":
+			return nil, nil, fmt.Errorf("tasks[%d]: task is required", i)
+		case task.Task == imageTaskName:
+			return nil, nil, fmt.Errorf("tasks[%d]: %s is created by the builder, use image_options instead", i, imageTaskName)
+		case taskNames[task.Task]:
+			return nil, nil, fmt.Errorf("tasks[%d]: task %s is duplicated", i, task.Task)
+		}
+		taskNames[task.Task] = true
+
+		if task.When == "" {
+			task.When = aquariumv2.ApplicationState_ALLOCATED.String()
+		}
+		when, ok := aquariumv2.ApplicationState_Status_value[strings.ToUpper(task.When)]
+		if !ok {
+			return nil, nil, fmt.Errorf("tasks[%d]: unknown when state %q", i, task.When)
+		}
+		task.when = aquariumv2.ApplicationState_Status(when)
+		if _, err := newStruct(task.Options); err != nil {
+			return nil, nil, fmt.Errorf("tasks[%d]: invalid options: %v", i, err)
+		}
+	}
+	switch b.config.Protocol {
+	case ProtocolConnect, ProtocolGRPC, ProtocolGRPCWeb:
+	default:
+		return nil, nil, fmt.Errorf("protocol %q is not supported, use %s, %s or %s",
+			b.config.Protocol, ProtocolConnect, ProtocolGRPC, ProtocolGRPCWeb)
+	}
+	if b.config.AllowInsecureTransport {
+		if !strings.HasPrefix(b.config.Endpoint, "http://") {
+			return nil, nil, fmt.Errorf("allow_insecure_transport can be used only with http:// endpoint")
+		}
+		warnings = append(warnings, "allow_insecure_transport is set, the API traffic is not encrypted: use it only for development and testing")
+	}
+	if _, err := newProxyFunc(&b.config); err != nil {
+		return nil, nil, err
+	}
+	// These transports connect to the endpoint directly, so the proxy would be silently ignored
+	if _, ok := unixSocketPath(b.config.Endpoint); (ok || b.config.AllowInsecureTransport) && b.config.ProxyURL != "" {
+		return nil, nil, fmt.Errorf("proxy_url can't be used with unix:// endpoint or allow_insecure_transport")
+	}
+	if b.config.CACertFile != "" || b.config.CACertPEM != "" {
+		if b.config.InsecureSkipTLSVerify {
+			warnings = append(warnings, "insecure_skip_tls_verify is set, so ca_cert_file/ca_cert_pem will be ignored")
+		} else if _, err := newTLSConfig(&b.config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if b.config.UserAgent == "" {
+		b.config.UserAgent = DefaultUserAgent(b.config.PackerCoreVersion)
 	}
 
 	// Set default SSH communicator
 	if b.config.Communicator.Type == "" {
 		b.config.Communicator.Type = "ssh"
 	}
+	switch b.config.Communicator.Type {
+	case "ssh":
+		switch {
+		case b.config.SSHInsecureHostKey && b.config.SSHHostKeyFingerprint != "":
+			return nil, nil, fmt.Errorf("ssh_host_key_fingerprint and ssh_insecure_host_key can't be used together")
+		case b.config.SSHInsecureHostKey:
+		case b.config.SSHHostKeyFingerprint == "":
+			// Any host key is accepted like before the verification was added
+			warnings = append(warnings, "ssh_host_key_fingerprint is not set, the SSH host key is not verified")
+		case !validSSHFingerprint(b.config.SSHHostKeyFingerprint):
+			return nil, nil, fmt.Errorf("invalid ssh_host_key_fingerprint %q, use SHA256:<base64> or MD5 aa:bb:... format",
+				b.config.SSHHostKeyFingerprint)
+		}
+		if b.config.Communicator.SSHKeyPairName != "" {
+			if b.config.Communicator.SSHPrivateKeyFile == "" {
+				return nil, nil, fmt.Errorf("ssh_private_key_file is required with ssh_keypair_name")
+			}
+			if b.config.SSHKeyPath != "" {
+				return nil, nil, fmt.Errorf("ssh_key_path can't be used with ssh_keypair_name, the key is not received from Fish")
+			}
+		}
+		// Communicator forwards the agent unless it's disabled, so it's turned into opt-in here
+		if b.config.SSHForwardAgent && b.config.Communicator.SSHDisableAgentForwarding {
+			return nil, nil, fmt.Errorf("ssh_forward_agent and ssh_disable_agent_forwarding can't be used together")
+		}
+		b.config.Communicator.SSHDisableAgentForwarding = !b.config.SSHForwardAgent
+		// The communicator passes them to the SSH client as is, so the typo is found only on connect
+		supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+		if err := validateSSHAlgorithms("ssh_ciphers", b.config.Communicator.SSHCiphers,
+			append(supported.Ciphers, insecure.Ciphers...)); err != nil {
+			return nil, nil, err
+		}
+		if err := validateSSHAlgorithms("ssh_key_exchange_algorithms", b.config.Communicator.SSHKEXAlgos,
+			append(supported.KeyExchanges, insecure.KeyExchanges...)); err != nil {
+			return nil, nil, err
+		}
+	case "winrm":
+	default:
+		return nil, nil, fmt.Errorf("communicator %q is not supported, use ssh or winrm", b.config.Communicator.Type)
+	}
+
+	// Catching the communicator misconfiguration before the resource allocation. The credentials
+	// could be received from the resource, so the username is not required here
+	sshUsername, winrmUser := b.config.Communicator.SSHUsername, b.config.Communicator.WinRMUser
+	if sshUsername == "" {
+		b.config.Communicator.SSHUsername = "aquarium"
+	}
+	if winrmUser == "" {
+		b.config.Communicator.WinRMUser = "aquarium"
+	}
+	commErrs := b.config.Communicator.Prepare(&b.config.ctx)
+	b.config.Communicator.SSHUsername, b.config.Communicator.WinRMUser = sshUsername, winrmUser
+	if len(commErrs) > 0 {
+		return nil, nil, packer.MultiErrorAppend(nil, commErrs...)
+	}
 
 	// Return the placeholder for the generated data that will become available to provisioners and post-processors.
-	buildGeneratedData := []string{"ApplicationUID", "ResourceUID", "SSHHost", "SSHPort"}
-	return buildGeneratedData, nil, nil
+	buildGeneratedData := []string{
+		"Endpoint", "BuildID", "LabelName", "LabelVersion", "DefinitionIndex", "ApplicationUID", "ResourceUID",
+		"ResourceIP", "ResourceNodeUID", "ResourceMetadata", "SSHHost", "SSHPort",
+		"WinRMHost", "WinRMPort", "ImageUID", "ImageName", "ImagePath",
+		"ImageTaskUID", "ImageTaskResult", "ImageStoredMetadata",
+	}
+	for _, task := range b.config.Tasks {
+		buildGeneratedData = append(buildGeneratedData, taskResultKey(task.Task))
+	}
+	return buildGeneratedData, warnings, nil
 }
 
-func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
-	// Create HTTP client
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: b.config.InsecureSkipTLSVerify,
+// steps returns the build steps, only StepConnectAPI uses the HTTP client to create the API client
+// which is passed to the other steps through the state bag
+func (b *Builder) steps(httpClient *http.Client) []multistep.Step {
+	// Cleanup is the first one to make sure we did not leave anything behind
+	steps := []multistep.Step{
+		&StepCleanup{
+			Config: &b.config,
+		},
+		// Steps cleanup is executed in reverse order, so the logs are collected before deallocation
+		&StepDumpLogs{
+			Config: &b.config,
 		},
 	}
-	httpClient := &http.Client{Transport: tr}
-
-	// Cleanup is the first one to make sure we did not leave anything behind
-	steps := []multistep.Step{&StepCleanup{
-		Config:     &b.config,
-		HTTPClient: httpClient,
-	}}
 
 	// Add AquariumFish steps
 	steps = append(steps,
@@ -150,63 +745,277 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 			Config:     &b.config,
 			HTTPClient: httpClient,
 		},
-		&StepFindLabel{
-			Config:     &b.config,
-			HTTPClient: httpClient,
-		},
-		&StepCreateApplication{
-			Config:     &b.config,
-			HTTPClient: httpClient,
-		},
-		&StepWaitForAllocation{
-			Config:     &b.config,
-			HTTPClient: httpClient,
-		},
-		&StepSetupSSH{
-			Config:     &b.config,
-			HTTPClient: httpClient,
-		},
-		&communicator.StepConnectSSH{
-			Config:    &b.config.Communicator,
-			Host:      commFunc(host),
-			SSHConfig: b.config.Communicator.SSHConfigFunc(),
-		},
+	)
+	// Resumed application with its resource and label are loaded by StepConnectAPI
+	if b.config.ResumeApplicationUID == "" {
+		// Dry run doesn't change anything in Fish, so the orphans are kept too
+		if !b.config.DryRun {
+			steps = append(steps, &StepCleanupOrphans{
+				Config: &b.config,
+			})
+		}
+		if b.config.LabelDefinitionFile != "" {
+			steps = append(steps, &StepCreateLabel{
+				Config: &b.config,
+			})
+		}
+		// In dry run the label from definition file is not created, so StepCreateLabel selects it
+		if !b.config.DryRun || b.config.LabelDefinitionFile == "" {
+			steps = append(steps, &StepFindLabel{
+				Config: &b.config,
+			})
+		}
+		if !b.config.SkipCapacityCheck {
+			steps = append(steps, &StepCheckCapacity{
+				Config: &b.config,
+			})
+		}
+		if b.config.DryRun {
+			return steps
+		}
+		steps = append(steps,
+			&StepCreateApplication{
+				Config: &b.config,
+			},
+			&StepWaitForAllocation{
+				Config: &b.config,
+			},
+		)
+	}
+
+	// Add communicator-specific steps
+	if b.config.Communicator.Type == "winrm" {
+		steps = append(steps,
+			&StepSetupWinRM{
+				Config: &b.config,
+			},
+			&communicator.StepConnectWinRM{
+				Config: &b.config.Communicator,
+				Host:   commFunc(winrmHost),
+			},
+		)
+	} else {
+		sshConfig := b.config.Communicator.SSHConfigFunc()
+		sshHost := host
+		if b.config.SSHUseOTP {
+			sshHost = sshOTPHostFunc(&b.config)
+		}
+		if b.config.SSHHostKeyFingerprint != "" {
+			sshConfig = sshHostKeyConfigFunc(b.config.SSHHostKeyFingerprint, sshConfig)
+		}
+		steps = append(steps,
+			&StepSetupSSH{
+				Config: &b.config,
+			},
+			&StepDebugSSH{
+				Config: &b.config,
+			},
+			&communicator.StepConnectSSH{
+				Config:    &b.config.Communicator,
+				Host:      commFunc(sshHost),
+				SSHConfig: sshConfig,
+			},
+		)
+	}
+
+	steps = append(steps,
 		new(commonsteps.StepProvision),
-		&StepCreateImage{
-			Config:     &b.config,
-			HTTPClient: httpClient,
+		&StepRunTasks{
+			Config: &b.config,
 		},
 	)
+	if !b.config.SkipCreateImage {
+		steps = append(steps, &StepCreateImage{
+			Config: &b.config,
+		})
+	}
+
+	return steps
+}
+
+func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	// The transport is configured once here, the API client created on it is shared through the state
+	httpClient, err := NewHTTPClient(&b.config)
+	if err != nil {
+		return nil, err
+	}
+	steps := b.steps(httpClient)
+	if b.metrics == nil {
+		b.metrics = newMetrics()
+		// The reporter is created for this build, so its connection is closed when the build is done
+		if closer, ok := b.metrics.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
 
 	// Setup the state bag and initial state for the steps
 	state := new(multistep.BasicStateBag)
+	if _, disabled := b.metrics.(noopMetrics); !disabled || b.config.buildTimeoutDuration > 0 {
+		steps = trackPhases(steps, b.metrics)
+		if hook != nil {
+			hook = &phaseHook{Hook: hook, state: state, metrics: b.metrics}
+		}
+	}
+	if b.config.ProvisionLogPath != "" && hook != nil {
+		hook = &provisionLogHook{Hook: hook, path: b.config.ProvisionLogPath}
+	}
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 	state.Put("config", &b.config)
 
 	// Set the value of the generated data that will become available to provisioners.
-	state.Put("generated_data", map[string]any{})
+	b.config.buildID = newBuildID(b.config.PackerBuildName)
+	state.Put("generated_data", map[string]any{"Endpoint": b.config.Endpoint, "BuildID": b.config.buildID})
 
 	// Run!
+	startedAt := time.Now()
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(ctx, state)
+	if b.config.buildTimeoutDuration > 0 {
+		buildCtx, cancel := context.WithTimeout(ctx, b.config.buildTimeoutDuration)
+		b.runner.Run(buildCtx, state)
+		// Steps are reporting the deadline as cancellation, so making the reason clear
+		if errors.Is(buildCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			phase, _ := state.Get("build_phase").(string)
+			ui.Error(fmt.Sprintf("Build timeout (%s) reached during %s", b.config.BuildTimeout, phase))
+			state.Put("error", fmt.Errorf("build timeout %s reached during %s", b.config.BuildTimeout, phase))
+		}
+		cancel()
+	} else {
+		b.runner.Run(ctx, state)
+	}
+	buildErr, _ := state.Get("error").(error)
+	b.metrics.RecordDuration("build", time.Since(startedAt))
+	b.metrics.RecordOutcome("build", buildErr)
+
+	// The summary is written for the failed builds too, so the automation could get the reason
+	if b.config.BuildSummaryPath != "" {
+		if err := writeBuildSummary(b.config.BuildSummaryPath, newBuildSummary(&b.config, state, startedAt)); err != nil {
+			ui.Error(err.Error())
+			if _, ok := state.GetOk("error"); !ok {
+				return nil, err
+			}
+		}
+	}
 
 	// If there was an error, return that
 	if err, ok := state.GetOk("error"); ok {
 		return nil, err.(error)
 	}
 
+	if b.config.DryRun {
+		ui.Say("Dry run completed, the build is able to start: no application was created")
+	}
+
 	// Get the generated data
 	generatedData := state.Get("generated_data").(map[string]any)
 
 	artifact := &Artifact{
+		Endpoint: b.config.Endpoint,
+		DryRun:   b.config.DryRun,
 		// Add the builder generated data to the artifact StateData so that post-processors
 		// can access them.
-		StateData: map[string]any{"generated_data": generatedData},
+		StateData: map[string]any{
+			"generated_data":      generatedData,
+			artifactConnectionKey: newArtifactConnection(&b.config),
+		},
+	}
+	if label, ok := state.GetOk("selected_label"); ok {
+		artifact.LabelName = label.(*aquariumv2.Label).GetName()
+		artifact.LabelVersion = label.(*aquariumv2.Label).GetVersion()
+	}
+	if app, ok := state.GetOk("application"); ok {
+		artifact.ApplicationUID = app.(*aquariumv2.Application).GetUid()
+	}
+	if resource, ok := state.GetOk("application_resource"); ok {
+		artifact.ResourceUID = resource.(*aquariumv2.ApplicationResource).GetUid()
+	}
+	if client, ok := state.GetOk("api_client"); ok {
+		artifact.client = client.(*APIClient)
+	}
+	if result, ok := state.GetOk("image_result"); ok {
+		image := result.(*ImageResult)
+		artifact.ImageUID = image.UID
+		artifact.ImageName = image.Name
+		if image.Path != "" {
+			artifact.ImagePaths = []string{image.Path}
+		}
 	}
 	return artifact, nil
 }
 
+// phaseStep records the name of the running step in state as build_phase and reports its metrics
+type phaseStep struct {
+	multistep.Step
+	metrics Metrics
+}
+
+// InnerStepName returns the wrapped step name for the debug pauses
+func (s *phaseStep) InnerStepName() string {
+	return reflect.Indirect(reflect.ValueOf(s.Step)).Type().Name()
+}
+
+// Run stores the phase and runs the wrapped step
+func (s *phaseStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	name := s.InnerStepName()
+	state.Put("build_phase", name)
+	start := time.Now()
+	action := s.Step.Run(ctx, state)
+	s.metrics.RecordDuration(name, time.Since(start))
+	var err error
+	if action == multistep.ActionHalt {
+		if err, _ = state.Get("error").(error); err == nil {
+			err = fmt.Errorf("%s halted the build", name)
+		}
+	}
+	s.metrics.RecordOutcome(name, err)
+	return action
+}
+
+// trackPhases wraps the steps to know which one was active when the build timeout was reached.
+// StepProvision is identified by type for -on-error=run-cleanup-provisioner, so it's tracked
+// by phaseHook instead
+func trackPhases(steps []multistep.Step, metrics Metrics) []multistep.Step {
+	tracked := make([]multistep.Step, len(steps))
+	for i, step := range steps {
+		if _, ok := step.(*commonsteps.StepProvision); ok {
+			tracked[i] = step
+			continue
+		}
+		tracked[i] = &phaseStep{Step: step, metrics: metrics}
+	}
+	return tracked
+}
+
+// phaseHook records the provisioning phase and reports its metrics
+type phaseHook struct {
+	packer.Hook
+	state   multistep.StateBag
+	metrics Metrics
+}
+
+// Run executes the wrapped hook
+func (h *phaseHook) Run(ctx context.Context, name string, ui packer.Ui, comm packer.Communicator, data any) error {
+	if name != packer.HookProvision {
+		return h.Hook.Run(ctx, name, ui, comm, data)
+	}
+	const phase = "StepProvision"
+	h.state.Put("build_phase", phase)
+	start := time.Now()
+	err := h.Hook.Run(ctx, name, ui, comm, data)
+	h.metrics.RecordDuration(phase, time.Since(start))
+	h.metrics.RecordOutcome(phase, err)
+	return err
+}
+
+// newBuildID returns the unique build identifier prefixed by the build name, so the applications of
+// the different sources in the same template could be distinguished
+func newBuildID(buildName string) string {
+	if buildName == "" {
+		buildName = "aquarium"
+	}
+	return buildName + "-" + uuid.TimeOrderedUUID()
+}
+
 // commFunc returns the host for SSH communication
 func commFunc(host func(multistep.StateBag) (string, error)) func(multistep.StateBag) (string, error) {
 	return host
@@ -220,3 +1029,118 @@ func host(state multistep.StateBag) (string, error) {
 	}
 	return sshHost.(string), nil
 }
+
+// winrmHost returns the WinRM host from the state
+func winrmHost(state multistep.StateBag) (string, error) {
+	winrmHost, ok := state.GetOk("winrm_host")
+	if !ok {
+		return "", fmt.Errorf("winrm_host not found in state")
+	}
+	return winrmHost.(string), nil
+}
+
+// loadApplicationMetadataFile merges the metadata file into ApplicationMetadata, inline keys are kept
+func (c *Config) loadApplicationMetadataFile() error {
+	data, err := os.ReadFile(c.ApplicationMetadataFile)
+	if err != nil {
+		return fmt.Errorf("unable to read application_metadata_file: %v", err)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("unable to parse application_metadata_file %s: %v", c.ApplicationMetadataFile, err)
+	}
+
+	if c.ApplicationMetadata == nil {
+		c.ApplicationMetadata = make(map[string]any, len(metadata))
+	}
+	for k, v := range metadata {
+		if _, ok := c.ApplicationMetadata[k]; !ok {
+			c.ApplicationMetadata[k] = v
+		}
+	}
+	return nil
+}
+
+// prepareMetadata renders the nested values of the metadata option and converts the strings unless
+// metadata_raw_strings is set
+func (c *Config) prepareMetadata(option string, metadata map[string]any) error {
+	// Decode interpolates only the top level string values, so the nested ones are rendered here
+	for k, v := range metadata {
+		if _, ok := v.(string); ok {
+			continue
+		}
+		rendered, err := interpolateValue(v, &c.ctx)
+		if err != nil {
+			return fmt.Errorf("unable to interpolate %s %q: %v", option, k, err)
+		}
+		metadata[k] = rendered
+	}
+	if !c.MetadataRawStrings {
+		for k, v := range metadata {
+			metadata[k] = coerceMetadataValue(v)
+		}
+	}
+	if _, err := newStruct(metadata); err != nil {
+		return fmt.Errorf("invalid %s: %v", option, err)
+	}
+	return nil
+}
+
+// metadataNumber matches the strings converted to numbers, leading zeros are kept to not break the
+// identifiers like "007" and long integers are kept since they lose precision as double
+var metadataNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]{0,14})(\.[0-9]+)?$`)
+
+// coerceMetadataValue converts the string values looking like bool, number or JSON array/object
+// to the corresponding types, the nested values are converted too
+func coerceMetadataValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		switch {
+		case v == "true" || v == "false":
+			return v == "true"
+		case metadataNumber.MatchString(v):
+			if number, err := strconv.ParseFloat(v, 64); err == nil {
+				return number
+			}
+		case strings.HasPrefix(v, "[") || strings.HasPrefix(v, "{"):
+			var decoded any
+			if err := json.Unmarshal([]byte(v), &decoded); err == nil {
+				return decoded
+			}
+		}
+	case map[string]any:
+		for k, item := range v {
+			v[k] = coerceMetadataValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = coerceMetadataValue(item)
+		}
+	}
+	return value
+}
+
+// interpolateValue renders the string values in the nested maps and slices
+func interpolateValue(value any, ctx *interpolate.Context) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return interpolate.Render(v, ctx)
+	case map[string]any:
+		for k, item := range v {
+			rendered, err := interpolateValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = rendered
+		}
+	case []any:
+		for i, item := range v {
+			rendered, err := interpolateValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+	}
+	return value, nil
+}
@@ -10,15 +10,137 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	MockOption          *string           `mapstructure:"mock" cty:"mock" hcl:"mock"`
+	PackerBuildName           *string                `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType         *string                `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion         *string                `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug               *bool                  `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce               *bool                  `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError             *string                `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars            map[string]string      `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars       []string               `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Endpoint                  *string                `mapstructure:"endpoint" required:"true" cty:"endpoint" hcl:"endpoint"`
+	Username                  *string                `mapstructure:"username" cty:"username" hcl:"username"`
+	Password                  *string                `mapstructure:"password" cty:"password" hcl:"password"`
+	InsecureSkipTLSVerify     *bool                  `mapstructure:"insecure_skip_tls_verify" cty:"insecure_skip_tls_verify" hcl:"insecure_skip_tls_verify"`
+	CACertFile                *string                `mapstructure:"ca_cert_file" cty:"ca_cert_file" hcl:"ca_cert_file"`
+	CACertPEM                 *string                `mapstructure:"ca_cert_pem" cty:"ca_cert_pem" hcl:"ca_cert_pem"`
+	AllowInsecureTransport    *bool                  `mapstructure:"allow_insecure_transport" cty:"allow_insecure_transport" hcl:"allow_insecure_transport"`
+	ProxyURL                  *string                `mapstructure:"proxy_url" cty:"proxy_url" hcl:"proxy_url"`
+	MaxIdleConns              *int                   `mapstructure:"max_idle_conns" cty:"max_idle_conns" hcl:"max_idle_conns"`
+	MaxConnsPerHost           *int                   `mapstructure:"max_conns_per_host" cty:"max_conns_per_host" hcl:"max_conns_per_host"`
+	IdleConnTimeout           *string                `mapstructure:"idle_conn_timeout" cty:"idle_conn_timeout" hcl:"idle_conn_timeout"`
+	APIPath                   *string                `mapstructure:"api_path" cty:"api_path" hcl:"api_path"`
+	Protocol                  *string                `mapstructure:"protocol" cty:"protocol" hcl:"protocol"`
+	APIVersion                *string                `mapstructure:"api_version" cty:"api_version" hcl:"api_version"`
+	UserAgent                 *string                `mapstructure:"user_agent" cty:"user_agent" hcl:"user_agent"`
+	CredentialsFile           *string                `mapstructure:"credentials_file" cty:"credentials_file" hcl:"credentials_file"`
+	Token                     *string                `mapstructure:"token" cty:"token" hcl:"token"`
+	AWSSecretID               *string                `mapstructure:"aws_secret_id" cty:"aws_secret_id" hcl:"aws_secret_id"`
+	AWSSecretRegion           *string                `mapstructure:"aws_secret_region" cty:"aws_secret_region" hcl:"aws_secret_region"`
+	OAuthTokenURL             *string                `mapstructure:"oauth_token_url" cty:"oauth_token_url" hcl:"oauth_token_url"`
+	OAuthClientID             *string                `mapstructure:"oauth_client_id" cty:"oauth_client_id" hcl:"oauth_client_id"`
+	OAuthClientSecret         *string                `mapstructure:"oauth_client_secret" cty:"oauth_client_secret" hcl:"oauth_client_secret"`
+	OAuthScopes               []string               `mapstructure:"oauth_scopes" cty:"oauth_scopes" hcl:"oauth_scopes"`
+	LabelName                 *string                `mapstructure:"label_name" cty:"label_name" hcl:"label_name"`
+	LabelVersion              *string                `mapstructure:"label_version" cty:"label_version" hcl:"label_version"`
+	LabelUID                  *string                `mapstructure:"label_uid" cty:"label_uid" hcl:"label_uid"`
+	LabelVersionConstraint    *string                `mapstructure:"label_version_constraint" cty:"label_version_constraint" hcl:"label_version_constraint"`
+	LabelIncludePrerelease    *bool                  `mapstructure:"label_include_prerelease" cty:"label_include_prerelease" hcl:"label_include_prerelease"`
+	DefinitionIndex           *int                   `mapstructure:"definition_index" cty:"definition_index" hcl:"definition_index"`
+	LabelDefinitionFile       *string                `mapstructure:"label_definition_file" cty:"label_definition_file" hcl:"label_definition_file"`
+	CleanupLabel              *bool                  `mapstructure:"cleanup_label" cty:"cleanup_label" hcl:"cleanup_label"`
+	ResumeApplicationUID      *string                `mapstructure:"resume_application_uid" cty:"resume_application_uid" hcl:"resume_application_uid"`
+	ConnectionTimeout         *string                `mapstructure:"connection_timeout" cty:"connection_timeout" hcl:"connection_timeout"`
+	ConnectionRetries         *int                   `mapstructure:"connection_retries" cty:"connection_retries" hcl:"connection_retries"`
+	RequestRetries            *int                   `mapstructure:"request_retries" cty:"request_retries" hcl:"request_retries"`
+	RetryBackoff              *string                `mapstructure:"retry_backoff" cty:"retry_backoff" hcl:"retry_backoff"`
+	RequestTimeout            *string                `mapstructure:"request_timeout" cty:"request_timeout" hcl:"request_timeout"`
+	AllocationTimeout         *string                `mapstructure:"allocation_timeout" cty:"allocation_timeout" hcl:"allocation_timeout"`
+	ImageTimeout              *string                `mapstructure:"image_timeout" cty:"image_timeout" hcl:"image_timeout"`
+	ImagePollInterval         *string                `mapstructure:"image_poll_interval" cty:"image_poll_interval" hcl:"image_poll_interval"`
+	CreateApplicationTimeout  *string                `mapstructure:"create_application_timeout" cty:"create_application_timeout" hcl:"create_application_timeout"`
+	AllocationRetries         *int                   `mapstructure:"allocation_retries" cty:"allocation_retries" hcl:"allocation_retries"`
+	DeallocationTimeout       *string                `mapstructure:"deallocation_timeout" cty:"deallocation_timeout" hcl:"deallocation_timeout"`
+	DeallocationPollInterval  *string                `mapstructure:"deallocation_poll_interval" cty:"deallocation_poll_interval" hcl:"deallocation_poll_interval"`
+	WaitForDeallocation       *bool                  `mapstructure:"wait_for_deallocation" cty:"wait_for_deallocation" hcl:"wait_for_deallocation"`
+	CleanupOrphansOlderThan   *string                `mapstructure:"cleanup_orphans_older_than" cty:"cleanup_orphans_older_than" hcl:"cleanup_orphans_older_than"`
+	BuildTimeout              *string                `mapstructure:"build_timeout" cty:"build_timeout" hcl:"build_timeout"`
+	ImageName                 *string                `mapstructure:"image_name" cty:"image_name" hcl:"image_name"`
+	ImageOptions              map[string]interface{} `mapstructure:"image_options" cty:"image_options" hcl:"image_options"`
+	ImageMetadata             map[string]interface{} `mapstructure:"image_metadata" cty:"image_metadata" hcl:"image_metadata"`
+	SkipCapacityCheck         *bool                  `mapstructure:"skip_capacity_check" cty:"skip_capacity_check" hcl:"skip_capacity_check"`
+	DryRun                    *bool                  `mapstructure:"dry_run" cty:"dry_run" hcl:"dry_run"`
+	SkipCreateImage           *bool                  `mapstructure:"skip_create_image" cty:"skip_create_image" hcl:"skip_create_image"`
+	Tasks                     []FlatTaskConfig       `mapstructure:"tasks" cty:"tasks" hcl:"tasks"`
+	Owner                     *string                `mapstructure:"owner" cty:"owner" hcl:"owner"`
+	ResourceCPU               *int                   `mapstructure:"resource_cpu" cty:"resource_cpu" hcl:"resource_cpu"`
+	ResourceRAM               *int                   `mapstructure:"resource_ram" cty:"resource_ram" hcl:"resource_ram"`
+	ResourceDisk              *string                `mapstructure:"resource_disk" cty:"resource_disk" hcl:"resource_disk"`
+	ApplicationMetadata       map[string]interface{} `mapstructure:"application_metadata" cty:"application_metadata" hcl:"application_metadata"`
+	MetadataRawStrings        *bool                  `mapstructure:"metadata_raw_strings" cty:"metadata_raw_strings" hcl:"metadata_raw_strings"`
+	ApplicationMetadataFile   *string                `mapstructure:"application_metadata_file" cty:"application_metadata_file" hcl:"application_metadata_file"`
+	MetadataKeyPrefix         *string                `mapstructure:"metadata_key_prefix" cty:"metadata_key_prefix" hcl:"metadata_key_prefix"`
+	SSHUseOTP                 *bool                  `mapstructure:"ssh_use_otp" cty:"ssh_use_otp" hcl:"ssh_use_otp"`
+	SSHKeyPath                *string                `mapstructure:"ssh_key_path" cty:"ssh_key_path" hcl:"ssh_key_path"`
+	SSHHostKeyFingerprint     *string                `mapstructure:"ssh_host_key_fingerprint" cty:"ssh_host_key_fingerprint" hcl:"ssh_host_key_fingerprint"`
+	SSHInsecureHostKey        *bool                  `mapstructure:"ssh_insecure_host_key" cty:"ssh_insecure_host_key" hcl:"ssh_insecure_host_key"`
+	SSHForwardAgent           *bool                  `mapstructure:"ssh_forward_agent" cty:"ssh_forward_agent" hcl:"ssh_forward_agent"`
+	ExposeSSHCredentials      *bool                  `mapstructure:"expose_ssh_credentials" cty:"expose_ssh_credentials" hcl:"expose_ssh_credentials"`
+	BuildSummaryPath          *string                `mapstructure:"build_summary_path" cty:"build_summary_path" hcl:"build_summary_path"`
+	FailureLogPath            *string                `mapstructure:"failure_log_path" cty:"failure_log_path" hcl:"failure_log_path"`
+	ProvisionLogPath          *string                `mapstructure:"provision_log_path" cty:"provision_log_path" hcl:"provision_log_path"`
+	KeepResourceOnError       *bool                  `mapstructure:"keep_resource_on_error" cty:"keep_resource_on_error" hcl:"keep_resource_on_error"`
+	DeallocateOnSuccess       *bool                  `mapstructure:"deallocate_on_success" cty:"deallocate_on_success" hcl:"deallocate_on_success"`
+	Type                      *string                `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect        *string                `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                   *string                `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                   *int                   `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername               *string                `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword               *string                `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName            *string                `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName   *string                `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType   *string                `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits   *int                   `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                []string               `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys    *bool                  `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos               []string               `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile         *string                `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile        *string                `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                    *bool                  `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                *string                `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout            *string                `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth              *bool                  `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding *bool                  `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts      *int                   `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost            *string                `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort            *int                   `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth       *bool                  `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername        *string                `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword        *string                `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive     *bool                  `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile  *string                `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile *string                `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod     *string                `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost              *string                `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort              *int                   `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername          *string                `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword          *string                `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval      *string                `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout       *string                `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels          []string               `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels           []string               `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey              []byte                 `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey             []byte                 `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                 *string                `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword             *string                `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                 *string                `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy              *bool                  `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                 *int                   `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout              *string                `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL               *bool                  `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure             *bool                  `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM              *bool                  `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	MockOption                *string                `mapstructure:"mock" undocumented:"true" cty:"mock" hcl:"mock"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -33,15 +155,164 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"mock":                       &hcldec.AttrSpec{Name: "mock", Type: cty.String, Required: false},
+		"packer_build_name":            &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":          &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":          &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                 &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                 &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":              &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":        &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":   &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"endpoint":                     &hcldec.AttrSpec{Name: "endpoint", Type: cty.String, Required: false},
+		"username":                     &hcldec.AttrSpec{Name: "username", Type: cty.String, Required: false},
+		"password":                     &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
+		"insecure_skip_tls_verify":     &hcldec.AttrSpec{Name: "insecure_skip_tls_verify", Type: cty.Bool, Required: false},
+		"ca_cert_file":                 &hcldec.AttrSpec{Name: "ca_cert_file", Type: cty.String, Required: false},
+		"ca_cert_pem":                  &hcldec.AttrSpec{Name: "ca_cert_pem", Type: cty.String, Required: false},
+		"allow_insecure_transport":     &hcldec.AttrSpec{Name: "allow_insecure_transport", Type: cty.Bool, Required: false},
+		"proxy_url":                    &hcldec.AttrSpec{Name: "proxy_url", Type: cty.String, Required: false},
+		"max_idle_conns":               &hcldec.AttrSpec{Name: "max_idle_conns", Type: cty.Number, Required: false},
+		"max_conns_per_host":           &hcldec.AttrSpec{Name: "max_conns_per_host", Type: cty.Number, Required: false},
+		"idle_conn_timeout":            &hcldec.AttrSpec{Name: "idle_conn_timeout", Type: cty.String, Required: false},
+		"api_path":                     &hcldec.AttrSpec{Name: "api_path", Type: cty.String, Required: false},
+		"protocol":                     &hcldec.AttrSpec{Name: "protocol", Type: cty.String, Required: false},
+		"api_version":                  &hcldec.AttrSpec{Name: "api_version", Type: cty.String, Required: false},
+		"user_agent":                   &hcldec.AttrSpec{Name: "user_agent", Type: cty.String, Required: false},
+		"credentials_file":             &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
+		"token":                        &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
+		"aws_secret_id":                &hcldec.AttrSpec{Name: "aws_secret_id", Type: cty.String, Required: false},
+		"aws_secret_region":            &hcldec.AttrSpec{Name: "aws_secret_region", Type: cty.String, Required: false},
+		"oauth_token_url":              &hcldec.AttrSpec{Name: "oauth_token_url", Type: cty.String, Required: false},
+		"oauth_client_id":              &hcldec.AttrSpec{Name: "oauth_client_id", Type: cty.String, Required: false},
+		"oauth_client_secret":          &hcldec.AttrSpec{Name: "oauth_client_secret", Type: cty.String, Required: false},
+		"oauth_scopes":                 &hcldec.AttrSpec{Name: "oauth_scopes", Type: cty.List(cty.String), Required: false},
+		"label_name":                   &hcldec.AttrSpec{Name: "label_name", Type: cty.String, Required: false},
+		"label_version":                &hcldec.AttrSpec{Name: "label_version", Type: cty.String, Required: false},
+		"label_uid":                    &hcldec.AttrSpec{Name: "label_uid", Type: cty.String, Required: false},
+		"label_version_constraint":     &hcldec.AttrSpec{Name: "label_version_constraint", Type: cty.String, Required: false},
+		"label_include_prerelease":     &hcldec.AttrSpec{Name: "label_include_prerelease", Type: cty.Bool, Required: false},
+		"definition_index":             &hcldec.AttrSpec{Name: "definition_index", Type: cty.Number, Required: false},
+		"label_definition_file":        &hcldec.AttrSpec{Name: "label_definition_file", Type: cty.String, Required: false},
+		"cleanup_label":                &hcldec.AttrSpec{Name: "cleanup_label", Type: cty.Bool, Required: false},
+		"resume_application_uid":       &hcldec.AttrSpec{Name: "resume_application_uid", Type: cty.String, Required: false},
+		"connection_timeout":           &hcldec.AttrSpec{Name: "connection_timeout", Type: cty.String, Required: false},
+		"connection_retries":           &hcldec.AttrSpec{Name: "connection_retries", Type: cty.Number, Required: false},
+		"request_retries":              &hcldec.AttrSpec{Name: "request_retries", Type: cty.Number, Required: false},
+		"retry_backoff":                &hcldec.AttrSpec{Name: "retry_backoff", Type: cty.String, Required: false},
+		"request_timeout":              &hcldec.AttrSpec{Name: "request_timeout", Type: cty.String, Required: false},
+		"allocation_timeout":           &hcldec.AttrSpec{Name: "allocation_timeout", Type: cty.String, Required: false},
+		"image_timeout":                &hcldec.AttrSpec{Name: "image_timeout", Type: cty.String, Required: false},
+		"image_poll_interval":          &hcldec.AttrSpec{Name: "image_poll_interval", Type: cty.String, Required: false},
+		"create_application_timeout":   &hcldec.AttrSpec{Name: "create_application_timeout", Type: cty.String, Required: false},
+		"allocation_retries":           &hcldec.AttrSpec{Name: "allocation_retries", Type: cty.Number, Required: false},
+		"deallocation_timeout":         &hcldec.AttrSpec{Name: "deallocation_timeout", Type: cty.String, Required: false},
+		"deallocation_poll_interval":   &hcldec.AttrSpec{Name: "deallocation_poll_interval", Type: cty.String, Required: false},
+		"wait_for_deallocation":        &hcldec.AttrSpec{Name: "wait_for_deallocation", Type: cty.Bool, Required: false},
+		"cleanup_orphans_older_than":   &hcldec.AttrSpec{Name: "cleanup_orphans_older_than", Type: cty.String, Required: false},
+		"build_timeout":                &hcldec.AttrSpec{Name: "build_timeout", Type: cty.String, Required: false},
+		"image_name":                   &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
+		"image_options":                &hcldec.AttrSpec{Name: "image_options", Type: cty.Map(cty.String), Required: false},
+		"image_metadata":               &hcldec.AttrSpec{Name: "image_metadata", Type: cty.Map(cty.String), Required: false},
+		"skip_capacity_check":          &hcldec.AttrSpec{Name: "skip_capacity_check", Type: cty.Bool, Required: false},
+		"dry_run":                      &hcldec.AttrSpec{Name: "dry_run", Type: cty.Bool, Required: false},
+		"skip_create_image":            &hcldec.AttrSpec{Name: "skip_create_image", Type: cty.Bool, Required: false},
+		"tasks":                        &hcldec.BlockListSpec{TypeName: "tasks", Nested: hcldec.ObjectSpec((*FlatTaskConfig)(nil).HCL2Spec())},
+		"owner":                        &hcldec.AttrSpec{Name: "owner", Type: cty.String, Required: false},
+		"resource_cpu":                 &hcldec.AttrSpec{Name: "resource_cpu", Type: cty.Number, Required: false},
+		"resource_ram":                 &hcldec.AttrSpec{Name: "resource_ram", Type: cty.Number, Required: false},
+		"resource_disk":                &hcldec.AttrSpec{Name: "resource_disk", Type: cty.String, Required: false},
+		"application_metadata":         &hcldec.AttrSpec{Name: "application_metadata", Type: cty.Map(cty.String), Required: false},
+		"metadata_raw_strings":         &hcldec.AttrSpec{Name: "metadata_raw_strings", Type: cty.Bool, Required: false},
+		"application_metadata_file":    &hcldec.AttrSpec{Name: "application_metadata_file", Type: cty.String, Required: false},
+		"metadata_key_prefix":          &hcldec.AttrSpec{Name: "metadata_key_prefix", Type: cty.String, Required: false},
+		"ssh_use_otp":                  &hcldec.AttrSpec{Name: "ssh_use_otp", Type: cty.Bool, Required: false},
+		"ssh_key_path":                 &hcldec.AttrSpec{Name: "ssh_key_path", Type: cty.String, Required: false},
+		"ssh_host_key_fingerprint":     &hcldec.AttrSpec{Name: "ssh_host_key_fingerprint", Type: cty.String, Required: false},
+		"ssh_insecure_host_key":        &hcldec.AttrSpec{Name: "ssh_insecure_host_key", Type: cty.Bool, Required: false},
+		"ssh_forward_agent":            &hcldec.AttrSpec{Name: "ssh_forward_agent", Type: cty.Bool, Required: false},
+		"expose_ssh_credentials":       &hcldec.AttrSpec{Name: "expose_ssh_credentials", Type: cty.Bool, Required: false},
+		"build_summary_path":           &hcldec.AttrSpec{Name: "build_summary_path", Type: cty.String, Required: false},
+		"failure_log_path":             &hcldec.AttrSpec{Name: "failure_log_path", Type: cty.String, Required: false},
+		"provision_log_path":           &hcldec.AttrSpec{Name: "provision_log_path", Type: cty.String, Required: false},
+		"keep_resource_on_error":       &hcldec.AttrSpec{Name: "keep_resource_on_error", Type: cty.Bool, Required: false},
+		"deallocate_on_success":        &hcldec.AttrSpec{Name: "deallocate_on_success", Type: cty.Bool, Required: false},
+		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
+		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
+		"ssh_host":                     &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
+		"ssh_port":                     &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
+		"ssh_username":                 &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"ssh_password":                 &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
+		"ssh_keypair_name":             &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_name":      &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_type":      &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
+		"temporary_key_pair_bits":      &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
+		"ssh_ciphers":                  &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
+		"ssh_clear_authorized_keys":    &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
+		"ssh_key_exchange_algorithms":  &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
+		"ssh_private_key_file":         &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
+		"ssh_certificate_file":         &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
+		"ssh_pty":                      &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
+		"ssh_timeout":                  &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
+		"ssh_wait_timeout":             &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
+		"ssh_agent_auth":               &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_disable_agent_forwarding": &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
+		"ssh_handshake_attempts":       &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
+		"ssh_bastion_host":             &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
+		"ssh_bastion_port":             &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
+		"ssh_bastion_agent_auth":       &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_bastion_username":         &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
+		"ssh_bastion_password":         &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
+		"ssh_bastion_interactive":      &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
+		"ssh_bastion_private_key_file": &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
+		"ssh_bastion_certificate_file": &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
+		"ssh_file_transfer_method":     &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
+		"ssh_proxy_host":               &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
+		"ssh_proxy_port":               &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
+		"ssh_proxy_username":           &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
+		"ssh_proxy_password":           &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
+		"ssh_keep_alive_interval":      &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
+		"ssh_read_write_timeout":       &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
+		"ssh_remote_tunnels":           &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_local_tunnels":            &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_public_key":               &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
+		"ssh_private_key":              &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
+		"winrm_username":               &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
+		"winrm_password":               &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
+		"winrm_host":                   &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
+		"winrm_no_proxy":               &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
+		"winrm_port":                   &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
+		"winrm_timeout":                &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
+		"winrm_use_ssl":                &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
+		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
+		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"mock":                         &hcldec.AttrSpec{Name: "mock", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatTaskConfig is an auto-generated flat version of TaskConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTaskConfig struct {
+	Task    *string                `mapstructure:"task" required:"true" cty:"task" hcl:"task"`
+	When    *string                `mapstructure:"when" cty:"when" hcl:"when"`
+	Options map[string]interface{} `mapstructure:"options" cty:"options" hcl:"options"`
+}
+
+// FlatMapstructure returns a new FlatTaskConfig.
+// FlatTaskConfig is an auto-generated flat version of TaskConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TaskConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTaskConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TaskConfig.
+// This spec is used by HCL to read the fields of TaskConfig.
+// The decoded values from this spec will then be applied to a FlatTaskConfig.
+func (*FlatTaskConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"task":    &hcldec.AttrSpec{Name: "task", Type: cty.String, Required: false},
+		"when":    &hcldec.AttrSpec{Name: "when", Type: cty.String, Required: false},
+		"options": &hcldec.AttrSpec{Name: "options", Type: cty.Map(cty.String), Required: false},
 	}
 	return s
 }
@@ -16,6 +16,7 @@ package aquarium
 
 import (
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -29,6 +30,9 @@ import (
 //go:embed test-fixtures/template.pkr.hcl
 var testBuilderHCL2Basic string
 
+//go:embed test-fixtures/template-manifest.pkr.hcl
+var testBuilderHCL2Manifest string
+
 // Run with: PACKER_ACC=1 go test -count 1 -v ./builder/aquarium/builder_acc_test.go  -timeout=120m
 func TestAccAquariumBuilder(t *testing.T) {
 	testCase := &acctest.PluginTestCase{
@@ -69,3 +73,60 @@ func TestAccAquariumBuilder(t *testing.T) {
 	}
 	acctest.TestPlugin(t, testCase)
 }
+
+// Run with: PACKER_ACC=1 AQUARIUM_ENDPOINT=... AQUARIUM_USERNAME=... AQUARIUM_PASSWORD=...
+// AQUARIUM_LABEL_NAME=... go test -count 1 -v ./builder/aquarium/ -run TestAccAquariumBuilderManifest -timeout=120m
+func TestAccAquariumBuilderManifest(t *testing.T) {
+	const manifestPath = "aquarium-manifest.json"
+	testCase := &acctest.PluginTestCase{
+		Name: "aquarium_builder_manifest_test",
+		Setup: func() error {
+			if os.Getenv("AQUARIUM_LABEL_NAME") == "" {
+				return fmt.Errorf("AQUARIUM_LABEL_NAME is required for the manifest test")
+			}
+			return nil
+		},
+		Teardown: func() error {
+			return os.Remove(manifestPath)
+		},
+		Template: testBuilderHCL2Manifest,
+		Type:     "aquarium-rest",
+		Check: func(buildCommand *exec.Cmd, logfile string) error {
+			if buildCommand.ProcessState != nil {
+				if buildCommand.ProcessState.ExitCode() != 0 {
+					return fmt.Errorf("Bad exit code. Logfile: %s", logfile)
+				}
+			}
+
+			data, err := os.ReadFile(manifestPath)
+			if err != nil {
+				return fmt.Errorf("Unable to read manifest %s: %v", manifestPath, err)
+			}
+			var manifest struct {
+				Builds []struct {
+					BuilderType string            `json:"builder_type"`
+					ArtifactID  string            `json:"artifact_id"`
+					CustomData  map[string]string `json:"custom_data"`
+				} `json:"builds"`
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return fmt.Errorf("Unable to parse manifest: %v", err)
+			}
+			if len(manifest.Builds) != 1 {
+				return fmt.Errorf("Expected 1 build in manifest, got: %s", data)
+			}
+			build := manifest.Builds[0]
+			if build.BuilderType != "aquarium-rest" {
+				return fmt.Errorf("Unexpected builder_type in manifest: %s", data)
+			}
+			if build.ArtifactID == "" {
+				return fmt.Errorf("Image UID is not recorded as artifact_id in manifest: %s", data)
+			}
+			if build.CustomData["label_name"] != os.Getenv("AQUARIUM_LABEL_NAME") {
+				return fmt.Errorf("Generated data is not available in manifest custom_data: %s", data)
+			}
+			return nil
+		},
+	}
+	acctest.TestPlugin(t, testCase)
+}
@@ -0,0 +1,660 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testConfig() map[string]any {
+	return map[string]any{
+		"endpoint":   "https://fish.example.com:8001/",
+		"username":   "admin",
+		"password":   "admin",
+		"label_name": "test-label",
+
+		"ssh_host_key_fingerprint": "SHA256:tHSpmicF4jz5BaSE7G0U71i1a75i6Skng0Zuw2O1By0",
+	}
+}
+
+func TestBuilderPrepare_MockDeprecated(t *testing.T) {
+	raw := testConfig()
+	raw["mock"] = "mock-config"
+
+	var b Builder
+	_, warnings, err := b.Prepare(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, w := range warnings {
+		if strings.Contains(w, "mock is deprecated and ignored") {
+			return
+		}
+	}
+	t.Fatalf("no mock deprecation warning in: %v", warnings)
+}
+
+func TestBuilderPrepare_MockConflict(t *testing.T) {
+	raw := testConfig()
+	raw["mock"] = "mock-config"
+	raw["token"] = "secret"
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "mock can't be used") {
+		t.Fatalf("expected mock conflict error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_ResourceOverrideUnsupported(t *testing.T) {
+	raw := testConfig()
+	raw["resource_cpu"] = 16
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("expected resources override error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_SSHHostKeyOptional(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "ssh_host_key_fingerprint")
+
+	var b Builder
+	_, warnings, err := b.Prepare(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(warnings, "ssh_host_key_fingerprint is not set, the SSH host key is not verified") {
+		t.Fatalf("no host key warning in: %v", warnings)
+	}
+
+	raw["ssh_insecure_host_key"] = true
+	b = Builder{}
+	if _, warnings, err = b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slices.Contains(warnings, "ssh_host_key_fingerprint is not set, the SSH host key is not verified") {
+		t.Fatalf("unexpected host key warning with ssh_insecure_host_key: %v", warnings)
+	}
+}
+
+func TestBuilderPrepare_SSHAlgorithms(t *testing.T) {
+	raw := testConfig()
+	raw["ssh_ciphers"] = []string{"aes256-gcm@openssh.com"}
+	raw["ssh_key_exchange_algorithms"] = []string{"curve25519-sha256", "diffie-hellman-group14-sha1"}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	raw["ssh_key_exchange_algorithms"] = []string{"curve25519-sha512"}
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "ssh_key_exchange_algorithms") {
+		t.Fatalf("expected unsupported algorithm error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_SSHForwardAgent(t *testing.T) {
+	var b Builder
+	if _, _, err := b.Prepare(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.config.Communicator.SSHDisableAgentForwarding {
+		t.Fatalf("agent forwarding should be disabled by default")
+	}
+
+	raw := testConfig()
+	raw["ssh_forward_agent"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.config.Communicator.SSHDisableAgentForwarding {
+		t.Fatalf("agent forwarding should be enabled with ssh_forward_agent")
+	}
+}
+
+func TestBuilderPrepare_SSHKeypairName(t *testing.T) {
+	raw := testConfig()
+	raw["ssh_keypair_name"] = "packer-build"
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "ssh_private_key_file is required") {
+		t.Fatalf("expected private key file error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_CommunicatorInvalid(t *testing.T) {
+	raw := testConfig()
+	raw["ssh_private_key_file"] = filepath.Join(t.TempDir(), "missing_key")
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "ssh_private_key_file is invalid") {
+		t.Fatalf("expected private key file error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_CommunicatorUsernameFromResource(t *testing.T) {
+	for _, communicatorType := range []string{"ssh", "winrm"} {
+		raw := testConfig()
+		raw["communicator"] = communicatorType
+
+		var b Builder
+		if _, _, err := b.Prepare(raw); err != nil {
+			t.Fatalf("%s: unexpected error: %v", communicatorType, err)
+		}
+		if b.config.Communicator.SSHUsername != "" || b.config.Communicator.WinRMUser != "" {
+			t.Fatalf("%s: username should be received from the resource", communicatorType)
+		}
+	}
+}
+
+func TestBuilderPrepare_MetadataCollision(t *testing.T) {
+	raw := testConfig()
+	raw["application_metadata"] = map[string]any{"packer_build_name": "custom", "PACKER_BUILD": "true"}
+
+	var b Builder
+	_, warnings, err := b.Prepare(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, w := range warnings {
+		// PACKER_BUILD is not the builder key anymore, so only packer_build_name collides
+		if strings.Contains(w, "overrides the builder metadata keys packer_build_name, change") {
+			return
+		}
+	}
+	t.Fatalf("no metadata collision warning in: %v", warnings)
+}
+
+func TestBuilderPrepare_BuildTimeoutWarnings(t *testing.T) {
+	tests := []struct {
+		buildTimeout string
+		warnings     []string
+	}{
+		{"1h", nil},
+		{"20m", []string{"allocation_timeout 30m exceeds build_timeout 20m"}},
+		{"5m", []string{"allocation_timeout 30m exceeds build_timeout 5m", "connection_timeout 10m exceeds build_timeout 5m"}},
+	}
+	for _, tt := range tests {
+		raw := testConfig()
+		raw["build_timeout"] = tt.buildTimeout
+
+		var b Builder
+		_, warnings, err := b.Prepare(raw)
+		if err != nil {
+			t.Fatalf("build_timeout %s: unexpected error: %v", tt.buildTimeout, err)
+		}
+		var found []string
+		for _, w := range warnings {
+			if strings.Contains(w, "exceeds build_timeout") {
+				found = append(found, w)
+			}
+		}
+		if len(found) != len(tt.warnings) {
+			t.Fatalf("build_timeout %s: unexpected warnings: %v", tt.buildTimeout, found)
+		}
+		for i, w := range tt.warnings {
+			if !strings.HasPrefix(found[i], w) {
+				t.Errorf("build_timeout %s: expected warning %q, got %q", tt.buildTimeout, w, found[i])
+			}
+		}
+	}
+}
+
+// Vault secrets are resolved by Packer core in the variables section and passed as user variables,
+// so they need to be interpolated for the own and squashed communicator fields during Decode
+func TestBuilderPrepare_MetadataTypes(t *testing.T) {
+	metadata := func() map[string]any {
+		return map[string]any{
+			"enabled":  "true",
+			"disabled": "false",
+			"cpu":      "42",
+			"ratio":    "-0.5",
+			"build_id": "007",
+			"long_id":  "12345678901234567890",
+			"version":  "1.2.3",
+			"list":     `["a", 1, true]`,
+			"object":   `{"key": "value"}`,
+			"broken":   "[not json",
+			"nested":   map[string]any{"flag": "true"},
+			"typed":    float64(3),
+			"name":     "True",
+		}
+	}
+	expected := map[string]any{
+		"enabled":  true,
+		"disabled": false,
+		"cpu":      float64(42),
+		"ratio":    -0.5,
+		"build_id": "007",
+		"long_id":  "12345678901234567890",
+		"version":  "1.2.3",
+		"list":     []any{"a", float64(1), true},
+		"object":   map[string]any{"key": "value"},
+		"broken":   "[not json",
+		"nested":   map[string]any{"flag": true},
+		"typed":    float64(3),
+		"name":     "True",
+	}
+
+	raw := testConfig()
+	raw["application_metadata"] = metadata()
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metaStruct, err := newStruct(b.config.ApplicationMetadata)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(metaStruct.AsMap(), expected) {
+		t.Fatalf("unexpected metadata: %v", metaStruct.AsMap())
+	}
+
+	raw = testConfig()
+	raw["application_metadata"] = metadata()
+	raw["metadata_raw_strings"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.config.ApplicationMetadata["enabled"] != "true" || b.config.ApplicationMetadata["cpu"] != "42" {
+		t.Fatalf("metadata should be kept as strings: %v", b.config.ApplicationMetadata)
+	}
+}
+
+func TestBuilderPrepare_ImageMetadata(t *testing.T) {
+	raw := testConfig()
+	raw["image_metadata"] = map[string]any{
+		"team":    "build",
+		"expiry":  "30",
+		"release": map[string]any{"commit": "{{user `commit`}}"},
+	}
+	raw["packer_user_variables"] = map[string]string{"commit": "abc123"}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"team":    "build",
+		"expiry":  float64(30),
+		"release": map[string]any{"commit": "abc123"},
+	}
+	if !reflect.DeepEqual(b.config.ImageMetadata, expected) {
+		t.Fatalf("unexpected image metadata: %v", b.config.ImageMetadata)
+	}
+}
+
+func TestBuilderPrepare_InterpolateUserVariables(t *testing.T) {
+	raw := testConfig()
+	raw["password"] = "{{user `fish_password`}}"
+	raw["ssh_password"] = "{{user `ssh_password`}}"
+	raw["packer_user_variables"] = map[string]string{
+		"fish_password": "secret-from-vault",
+		"ssh_password":  "ssh-secret-from-vault",
+	}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.config.Password != "secret-from-vault" {
+		t.Fatalf("password was not interpolated: %q", b.config.Password)
+	}
+	if b.config.Communicator.SSHPassword != "ssh-secret-from-vault" {
+		t.Fatalf("ssh_password was not interpolated: %q", b.config.Communicator.SSHPassword)
+	}
+}
+
+func TestBuilderPrepare_LabelVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		valid   bool
+	}{
+		{"literal", "3", true},
+		{"interpolated", "{{user `label_version`}}", true},
+		{"interpolated label name", "{{user `label_name`}}", false},
+		{"last", "last", true},
+		{"zero", "0", false},
+		{"non numeric", "v3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := testConfig()
+			raw["label_name"] = "{{user `label_name`}}"
+			raw["label_version"] = tt.version
+			raw["packer_user_variables"] = map[string]string{
+				"label_name":    "macos-15",
+				"label_version": "7",
+			}
+
+			var b Builder
+			_, _, err := b.Prepare(raw)
+			if !tt.valid {
+				if err == nil || !strings.Contains(err.Error(), "label_version") {
+					t.Fatalf("expected label_version error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if b.config.LabelName != "macos-15" {
+				t.Fatalf("label_name was not interpolated: %q", b.config.LabelName)
+			}
+			if strings.Contains(b.config.LabelVersion, "{{") {
+				t.Fatalf("label_version was not interpolated: %q", b.config.LabelVersion)
+			}
+		})
+	}
+}
+
+// Packer SDK allows the vault function only in the variables section, so make sure the user
+// gets the clear error instead of the empty password
+func TestBuilderPrepare_VaultOutsideVariables(t *testing.T) {
+	raw := testConfig()
+	raw["password"] = "{{ vault `secret/fish` `password` }}"
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err == nil || !strings.Contains(err.Error(), "variables section") {
+		t.Fatalf("expected vault usage error, got: %v", err)
+	}
+}
+
+func TestBuilderPrepare_AWSSecret(t *testing.T) {
+	getSecret := getAWSSecret
+	t.Cleanup(func() { getAWSSecret = getSecret })
+	getAWSSecret = func(secretID, region string) (string, error) {
+		if secretID != "fish/ci" || region != "us-west-2" {
+			t.Fatalf("unexpected secret %q in region %q", secretID, region)
+		}
+		return `{"username": "ci", "password": "secret"}`, nil
+	}
+
+	raw := testConfig()
+	delete(raw, "username")
+	delete(raw, "password")
+	raw["aws_secret_id"] = "fish/ci"
+	raw["aws_secret_region"] = "us-west-2"
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.config.Username != "ci" || b.config.Password != "secret" {
+		t.Fatalf("credentials were not taken from secret: %q/%q", b.config.Username, b.config.Password)
+	}
+}
+
+type testUserService struct {
+	aquariumv2connect.UnimplementedUserServiceHandler
+}
+
+func (s *testUserService) GetMe(ctx context.Context, req *connect.Request[aquariumv2.UserServiceGetMeRequest]) (*connect.Response[aquariumv2.UserServiceGetMeResponse], error) {
+	return connect.NewResponse(&aquariumv2.UserServiceGetMeResponse{Status: true, Data: &aquariumv2.User{Name: "admin"}}), nil
+}
+
+func TestBuilderSteps_DryRun(t *testing.T) {
+	raw := testConfig()
+	raw["dry_run"] = true
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var found []string
+	for _, step := range b.steps(&http.Client{}) {
+		switch step.(type) {
+		case *StepConnectAPI, *StepFindLabel, *StepCheckCapacity:
+			found = append(found, fmt.Sprintf("%T", step))
+		case *StepCleanup, *StepDumpLogs:
+		default:
+			t.Fatalf("step %T should not be executed in dry run", step)
+		}
+	}
+	if len(found) != 3 {
+		t.Fatalf("expected connect, find label and capacity check steps, got: %v", found)
+	}
+}
+
+func TestBuilderSteps_Resume(t *testing.T) {
+	raw := testConfig()
+	raw["resume_application_uid"] = "app-uid"
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, step := range b.steps(&http.Client{}) {
+		switch step.(type) {
+		case *StepCleanupOrphans, *StepFindLabel, *StepCheckCapacity, *StepCreateApplication, *StepWaitForAllocation:
+			t.Fatalf("step %T should not be executed for the resumed application", step)
+		}
+	}
+
+	raw["dry_run"] = true
+	b = Builder{}
+	if _, _, err := b.Prepare(raw); err == nil {
+		t.Fatalf("expected resume_application_uid and dry_run conflict error")
+	}
+}
+
+// countingTransport counts the requests passed through the configured transport
+type countingTransport struct {
+	base     http.RoundTripper
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return t.base.RoundTrip(req)
+}
+
+func TestBuilderSteps_SharedAPIClient(t *testing.T) {
+	var b Builder
+	if _, _, err := b.Prepare(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only StepConnectAPI is allowed to have the HTTP client, others use the API client from state
+	httpClientType := reflect.TypeOf(&http.Client{})
+	for _, step := range b.steps(&http.Client{}) {
+		if _, ok := step.(*StepConnectAPI); ok {
+			continue
+		}
+		v := reflect.Indirect(reflect.ValueOf(step))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Type() == httpClientType {
+				t.Fatalf("step %T has own HTTP client field %s", step, v.Type().Field(i).Name)
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewUserServiceHandler(&testUserService{}))
+	mux.Handle(aquariumv2connect.NewLabelServiceHandler(&testLabelService{labels: []*aquariumv2.Label{newTestLabel("label-v1", 1)}}))
+	mux.Handle(aquariumv2connect.NewApplicationServiceHandler(&testApplicationService{}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	transport := &countingTransport{base: server.Client().Transport}
+	config := &Config{
+		Endpoint:                  server.URL + "/",
+		Username:                  "admin",
+		Password:                  "admin",
+		LabelName:                 "test-label",
+		ConnectionRetries:         1,
+		connectionTimeoutDuration: time.Minute,
+	}
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("generated_data", map[string]any{})
+
+	steps := []multistep.Step{
+		&StepConnectAPI{Config: config, HTTPClient: &http.Client{Transport: transport}},
+		&StepFindLabel{Config: config},
+		&StepCreateApplication{Config: config},
+	}
+	var client *APIClient
+	for _, step := range steps {
+		before := transport.requests.Load()
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("step %T unexpected action %v: %v", step, action, state.Get("error"))
+		}
+		if transport.requests.Load() == before {
+			t.Fatalf("step %T did not use the configured transport", step)
+		}
+		current := state.Get("api_client").(*APIClient)
+		if client == nil {
+			client = current
+		} else if current != client {
+			t.Fatalf("step %T replaced the API client in state", step)
+		}
+	}
+}
+
+// waitStep blocks until the build context is done
+type waitStep struct {
+	cleaned bool
+}
+
+func (s *waitStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	<-ctx.Done()
+	return multistep.ActionHalt
+}
+
+func (s *waitStep) Cleanup(state multistep.StateBag) {
+	s.cleaned = true
+}
+
+func TestTrackPhases(t *testing.T) {
+	step := &waitStep{}
+	state := new(multistep.BasicStateBag)
+	runner := &multistep.BasicRunner{Steps: trackPhases([]multistep.Step{step}, noopMetrics{})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	runner.Run(ctx, state)
+
+	if phase := state.Get("build_phase"); phase != "waitStep" {
+		t.Fatalf("unexpected build phase: %v", phase)
+	}
+	if !step.cleaned {
+		t.Fatalf("wrapped step cleanup was not executed")
+	}
+}
+
+// lockedApplicationService allows the parallel builds to create the applications in the same fake Fish
+type lockedApplicationService struct {
+	testApplicationService
+	mu sync.Mutex
+}
+
+func (s *lockedApplicationService) Create(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceCreateRequest]) (*connect.Response[aquariumv2.ApplicationServiceCreateResponse], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.testApplicationService.Create(ctx, req)
+}
+
+// The sources of the same template are running in parallel in one plugin process, so the builders
+// should not share anything except the Fish they talk to
+func TestBuilder_ConcurrentSources(t *testing.T) {
+	labels := map[string]*aquariumv2.Label{"label-a": newTestLabel("label-a-uid", 1), "label-b": newTestLabel("label-b-uid", 2)}
+	var list []*aquariumv2.Label
+	for name, label := range labels {
+		label.Name = name
+		list = append(list, label)
+	}
+	service := &lockedApplicationService{}
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewUserServiceHandler(&testUserService{}))
+	mux.Handle(aquariumv2connect.NewLabelServiceHandler(&testLabelService{labels: list}))
+	mux.Handle(aquariumv2connect.NewApplicationServiceHandler(service))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	const builds = 10
+	states := make([]*multistep.BasicStateBag, builds)
+	var wg sync.WaitGroup
+	for i := range builds {
+		raw := testConfig()
+		raw["endpoint"] = server.URL
+		raw["api_path"] = "/"
+		raw["label_name"] = []string{"label-a", "label-b"}[i%2]
+		raw["packer_build_name"] = fmt.Sprintf("source-%d", i)
+		b := &Builder{}
+		if _, _, err := b.Prepare(raw); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		states[i] = new(multistep.BasicStateBag)
+		states[i].Put("ui", packersdk.TestUi(t))
+		states[i].Put("generated_data", map[string]any{})
+		wg.Add(1)
+		go func(state *multistep.BasicStateBag) {
+			defer wg.Done()
+			runner := &multistep.BasicRunner{Steps: []multistep.Step{
+				&StepConnectAPI{Config: &b.config, HTTPClient: server.Client()},
+				&StepFindLabel{Config: &b.config},
+				&StepCreateApplication{Config: &b.config},
+			}}
+			runner.Run(context.Background(), state)
+		}(states[i])
+	}
+	wg.Wait()
+
+	applications := map[string]bool{}
+	for i, state := range states {
+		if err, ok := state.GetOk("error"); ok {
+			t.Fatalf("build %d failed: %v", i, err)
+		}
+		expected := labels[[]string{"label-a", "label-b"}[i%2]]
+		if label := state.Get("selected_label").(*aquariumv2.Label); label.GetUid() != expected.GetUid() {
+			t.Fatalf("build %d selected label %s instead of %s", i, label.GetUid(), expected.GetUid())
+		}
+		application := state.Get("application").(*aquariumv2.Application)
+		if application.GetLabelUid() != expected.GetUid() {
+			t.Fatalf("build %d created application with label %s instead of %s", i, application.GetLabelUid(), expected.GetUid())
+		}
+		if applications[application.GetUid()] {
+			t.Fatalf("build %d got the application %s of another build", i, application.GetUid())
+		}
+		applications[application.GetUid()] = true
+	}
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	connect "connectrpc.com/connect"
+	"github.com/hashicorp/go-version"
+)
+
+// fishModulePath is the module the RPC clients are generated from
+const fishModulePath = "github.com/adobe/aquarium-fish"
+
+// staticAccessVersion is the Fish API version which knows the static flag of GetResourceAccess
+var staticAccessVersion = version.Must(version.NewVersion("0.9.0"))
+
+// FishAPIVersion returns the version of the Fish API the plugin RPC clients are generated from
+func FishAPIVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == fishModulePath {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+// WithAPIVersion sets the version of the target Fish server API, the optional request fields
+// unknown to this version are not sent
+func WithAPIVersion(apiVersion *version.Version) APIClientOption {
+	return func(c *APIClient) {
+		c.apiVersion = apiVersion
+	}
+}
+
+// supports checks if the target server API is not older than the provided version, the latest
+// API is assumed when api_version is not set
+func (c *APIClient) supports(minVersion *version.Version) bool {
+	return c.apiVersion == nil || c.apiVersion.GreaterThanOrEqual(minVersion)
+}
+
+// newCompatibilityInterceptor explains the errors caused by the server API version mismatch,
+// the older servers reject the unknown RPC methods and request fields
+func newCompatibilityInterceptor() connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			resp, err := next(ctx, req)
+			var connectErr *connect.Error
+			if err == nil || !errors.As(err, &connectErr) {
+				return resp, err
+			}
+			switch connectErr.Code() {
+			case connect.CodeUnimplemented, connect.CodeInvalidArgument:
+				return resp, connect.NewError(connectErr.Code(), fmt.Errorf("%s (the server API could be older than "+
+					"the plugin one %s, set api_version to the server version if so)", connectErr.Message(), FishAPIVersion()))
+			}
+			return resp, err
+		}
+	}
+}
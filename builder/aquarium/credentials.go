@@ -0,0 +1,167 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/bgentry/go-netrc/netrc"
+	awssm "github.com/hashicorp/packer-plugin-sdk/template/interpolate/aws/secretsmanager"
+)
+
+// credentialsFile is the structure of the file pointed by credentials_file option
+type credentialsFile struct {
+	Endpoint string `json:"endpoint"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loadCredentialsFile fills the empty connection settings from credentials_file, returns false
+// if the file does not exist
+func (c *Config) loadCredentialsFile() (bool, error) {
+	data, err := os.ReadFile(c.CredentialsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read credentials_file: %v", err)
+	}
+
+	var creds credentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return false, fmt.Errorf("unable to parse credentials_file %q: %v", c.CredentialsFile, err)
+	}
+
+	fillEmpty(&c.Endpoint, creds.Endpoint)
+	fillEmpty(&c.Username, creds.Username)
+	fillEmpty(&c.Password, creds.Password)
+
+	return true, nil
+}
+
+// awsSecret is the structure of the AWS Secrets Manager secret pointed by aws_secret_id option
+type awsSecret struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// getAWSSecret returns the raw AWS Secrets Manager secret value, the AWS client is provided by the
+// packer SDK (used by aws_secretsmanager template function) so no additional dependency is needed
+var getAWSSecret = func(secretID, region string) (string, error) {
+	return awssm.New(&awssm.AWSConfig{Region: region}).GetSecret(&awssm.SecretSpec{Name: secretID}, true)
+}
+
+// loadAWSSecret fills the empty auth settings from the JSON secret stored in AWS Secrets Manager,
+// AWS credentials are taken from the standard AWS environment, shared config or instance role
+func (c *Config) loadAWSSecret() error {
+	data, err := getAWSSecret(c.AWSSecretID, c.AWSSecretRegion)
+	if err != nil {
+		return fmt.Errorf("unable to get aws_secret_id %q: %v", c.AWSSecretID, err)
+	}
+
+	var secret awsSecret
+	if err := json.Unmarshal([]byte(data), &secret); err != nil {
+		return fmt.Errorf("unable to parse aws_secret_id %q, expecting JSON with username/password or token: %v", c.AWSSecretID, err)
+	}
+	if secret.Token == "" && (secret.Username == "" || secret.Password == "") {
+		return fmt.Errorf("aws_secret_id %q contains neither username/password nor token", c.AWSSecretID)
+	}
+
+	// Token takes precedence over username/password, so it's used only when they are not configured
+	if c.Username == "" && c.Password == "" {
+		fillEmpty(&c.Token, secret.Token)
+	}
+	fillEmpty(&c.Username, secret.Username)
+	fillEmpty(&c.Password, secret.Password)
+
+	return nil
+}
+
+// loadEnv fills the empty connection settings from the environment variables and returns the
+// list of variables used. Explicit config always takes precedence over the environment.
+func (c *Config) loadEnv() (used []string) {
+	for _, v := range []struct {
+		field *string
+		env   string
+	}{
+		{&c.Endpoint, "AQUARIUM_ENDPOINT"},
+		{&c.Username, "AQUARIUM_USERNAME"},
+		{&c.Password, "AQUARIUM_PASSWORD"},
+	} {
+		if fillEmpty(v.field, os.Getenv(v.env)) {
+			used = append(used, v.env)
+		}
+	}
+	return used
+}
+
+// loadNetrc fills the empty username and password from the netrc machine entry of the endpoint
+// host. The netrc location could be overridden by NETRC environment variable.
+func (c *Config) loadNetrc() error {
+	if c.Endpoint == "" || c.Token != "" || c.OAuthTokenURL != "" {
+		return nil
+	}
+	if c.Username != "" && c.Password != "" {
+		return nil
+	}
+
+	endpointURL, err := url.Parse(c.Endpoint)
+	if err != nil || endpointURL.Hostname() == "" {
+		// Endpoint validation will report the problem
+		return nil
+	}
+
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	machine, err := netrc.FindMachine(path, endpointURL.Hostname())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse netrc file %q: %v", path, err)
+	}
+	// Default entry is not related to the endpoint, so skipping it
+	if machine == nil || machine.IsDefault() {
+		return nil
+	}
+
+	fillEmpty(&c.Username, machine.Login)
+	fillEmpty(&c.Password, machine.Password)
+
+	return nil
+}
+
+// fillEmpty sets the field to value if the field is empty, returns true if it was set
+func fillEmpty(field *string, value string) bool {
+	if *field != "" || value == "" {
+		return false
+	}
+	*field = value
+	return true
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestFish starts fake Fish server with the provided connect handlers and returns the client to it
+func newTestFish(t *testing.T, handlers func(mux *http.ServeMux)) *APIClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	handlers(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewAPIClient(server.URL, APIAuth{Username: "admin", Password: "admin"}, server.Client())
+}
+
+// newTestState prepares the state bag with the basic values used by the steps
+func newTestState(t *testing.T, client *APIClient) *multistep.BasicStateBag {
+	t.Helper()
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packersdk.TestUi(t))
+	state.Put("api_client", client)
+	state.Put("generated_data", map[string]any{})
+	return state
+}
+
+type testApplicationService struct {
+	aquariumv2connect.UnimplementedApplicationServiceHandler
+	created     []*aquariumv2.Application
+	deallocated []string
+	hasDeadline bool
+	// Statuses returned by GetState one by one, the last one is repeated
+	statuses   []aquariumv2.ApplicationState_Status
+	stateCalls int
+	// GetResource returns no resource for this number of calls
+	resourceAfter int
+	resourceCalls int
+	// Definition index of the resource returned by GetResource
+	resourceDefinition int32
+	// Tasks returned by GetTask
+	tasks map[string]*aquariumv2.ApplicationTask
+}
+
+func (s *testApplicationService) Get(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceGetRequest]) (*connect.Response[aquariumv2.ApplicationServiceGetResponse], error) {
+	return connect.NewResponse(&aquariumv2.ApplicationServiceGetResponse{Status: true, Data: &aquariumv2.Application{
+		Uid:      req.Msg.GetApplicationUid(),
+		LabelUid: "label-v1",
+	}}), nil
+}
+
+func (s *testApplicationService) GetTask(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceGetTaskRequest]) (*connect.Response[aquariumv2.ApplicationServiceGetTaskResponse], error) {
+	task, ok := s.tasks[req.Msg.GetApplicationTaskUid()]
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("task not found"))
+	}
+	return connect.NewResponse(&aquariumv2.ApplicationServiceGetTaskResponse{Status: true, Data: task}), nil
+}
+
+func (s *testApplicationService) GetState(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceGetStateRequest]) (*connect.Response[aquariumv2.ApplicationServiceGetStateResponse], error) {
+	status := s.statuses[min(s.stateCalls, len(s.statuses)-1)]
+	s.stateCalls++
+	return connect.NewResponse(&aquariumv2.ApplicationServiceGetStateResponse{Status: true, Data: &aquariumv2.ApplicationState{
+		ApplicationUid: req.Msg.GetApplicationUid(),
+		Status:         status,
+		Description:    "test state",
+	}}), nil
+}
+
+func (s *testApplicationService) GetResource(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceGetResourceRequest]) (*connect.Response[aquariumv2.ApplicationServiceGetResourceResponse], error) {
+	s.resourceCalls++
+	if s.resourceCalls <= s.resourceAfter {
+		return connect.NewResponse(&aquariumv2.ApplicationServiceGetResourceResponse{Status: true}), nil
+	}
+	return connect.NewResponse(&aquariumv2.ApplicationServiceGetResourceResponse{Status: true, Data: &aquariumv2.ApplicationResource{
+		Uid:             "res-uid",
+		ApplicationUid:  req.Msg.GetApplicationUid(),
+		DefinitionIndex: s.resourceDefinition,
+	}}), nil
+}
+
+func (s *testApplicationService) Create(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceCreateRequest]) (*connect.Response[aquariumv2.ApplicationServiceCreateResponse], error) {
+	app := req.Msg.GetApplication()
+	app.Uid = fmt.Sprintf("app-%d", len(s.created)+1)
+	s.created = append(s.created, app)
+	return connect.NewResponse(&aquariumv2.ApplicationServiceCreateResponse{Status: true, Data: app}), nil
+}
+
+// CreateTask completes the ALLOCATED tasks right away like Fish does for the allocated application
+func (s *testApplicationService) CreateTask(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceCreateTaskRequest]) (*connect.Response[aquariumv2.ApplicationServiceCreateTaskResponse], error) {
+	if s.tasks == nil {
+		s.tasks = make(map[string]*aquariumv2.ApplicationTask)
+	}
+	task := req.Msg.GetTask()
+	task.Uid = fmt.Sprintf("task-%d", len(s.tasks)+1)
+	s.tasks[task.Uid] = task
+	created := proto.Clone(task).(*aquariumv2.ApplicationTask)
+	if task.GetWhen() == aquariumv2.ApplicationState_ALLOCATED {
+		task.Result, _ = structpb.NewStruct(map[string]any{"done": true})
+	}
+	return connect.NewResponse(&aquariumv2.ApplicationServiceCreateTaskResponse{Status: true, Data: created}), nil
+}
+
+func (s *testApplicationService) Deallocate(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceDeallocateRequest]) (*connect.Response[aquariumv2.ApplicationServiceDeallocateResponse], error) {
+	_, s.hasDeadline = ctx.Deadline()
+	s.deallocated = append(s.deallocated, req.Msg.GetApplicationUid())
+	return connect.NewResponse(&aquariumv2.ApplicationServiceDeallocateResponse{Status: true}), nil
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	connect "connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// debugLogging is enabled by PACKER_LOG, the plugin log is written to the Packer log in this case
+var debugLogging = os.Getenv("PACKER_LOG") != "" && os.Getenv("PACKER_LOG") != "0"
+
+// logPayloads is enabled by AQUARIUM_LOG_PAYLOADS to add the redacted RPC payloads to the debug log
+var logPayloads = os.Getenv("AQUARIUM_LOG_PAYLOADS") != "" && os.Getenv("AQUARIUM_LOG_PAYLOADS") != "0"
+
+// redactedFields are cleared in the logged payloads, they contain the credentials, password hashes
+// and the user provided metadata which can hold secrets
+var redactedFields = map[protoreflect.Name]bool{
+	"authentication": true,
+	"password":       true,
+	"hash":           true,
+	"key":            true,
+	"token":          true,
+	"refresh_token":  true,
+	"metadata":       true,
+}
+
+// logDebug writes verbose diagnostics which are useful only when PACKER_LOG is enabled
+func logDebug(format string, args ...any) {
+	if debugLogging {
+		log.Printf("[DEBUG] "+format, args...)
+	}
+}
+
+// logInfo writes the notable events, like retries and reconnections
+func logInfo(format string, args ...any) {
+	log.Printf("[INFO] "+format, args...)
+}
+
+// loggingInterceptor logs the RPC method names, durations and status codes
+type loggingInterceptor struct{}
+
+func (loggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		start := time.Now()
+		if logPayloads {
+			logDebug("RPC %s request: %v", req.Spec().Procedure, redactPayload(req.Any()))
+		}
+		resp, err := next(ctx, req)
+		if err != nil {
+			logDebug("RPC %s failed in %s: %s", req.Spec().Procedure, time.Since(start), connect.CodeOf(err))
+			return resp, err
+		}
+		logDebug("RPC %s completed in %s: ok", req.Spec().Procedure, time.Since(start))
+		if logPayloads {
+			logDebug("RPC %s response: %v", req.Spec().Procedure, redactPayload(resp.Any()))
+		}
+		return resp, nil
+	}
+}
+
+// redactPayload returns the copy of the message without the redactedFields
+func redactPayload(payload any) any {
+	msg, ok := payload.(proto.Message)
+	if !ok {
+		return payload
+	}
+	msg = proto.Clone(msg)
+	redactMessage(msg.ProtoReflect())
+	return msg
+}
+
+// redactMessage clears the redactedFields of the message and the nested ones
+func redactMessage(msg protoreflect.Message) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case redactedFields[fd.Name()]:
+			msg.Clear(fd)
+		case fd.Message() != nil && fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				redactMessage(v.List().Get(i).Message())
+			}
+		case fd.Message() != nil && !fd.IsMap():
+			redactMessage(v.Message())
+		}
+		return true
+	})
+}
+
+func (loggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		logDebug("RPC %s stream opened", spec.Procedure)
+		return next(ctx, spec)
+	}
+}
+
+func (loggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+)
+
+// Makes sure the label credentials never get to the debug log, even with the payloads logging
+func TestLoggingInterceptor_Redacts(t *testing.T) {
+	debug, payloads := debugLogging, logPayloads
+	t.Cleanup(func() { debugLogging, logPayloads = debug, payloads })
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	label := newTestLabel("label-v1", 1)
+	label.Definitions[0].Authentication = &aquariumv2.Authentication{Username: "packer", Password: "label-secret"}
+	labels := &testLabelService{labels: []*aquariumv2.Label{label}}
+
+	for _, payloads := range []bool{false, true} {
+		out.Reset()
+		debugLogging, logPayloads = true, payloads
+		client := newTestFish(t, func(mux *http.ServeMux) {
+			mux.Handle(aquariumv2connect.NewLabelServiceHandler(labels))
+		})
+		if _, err := client.GetLabel(context.Background(), "label-v1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		logged := out.String()
+		if !strings.Contains(logged, aquariumv2connect.LabelServiceGetProcedure+" completed") {
+			t.Fatalf("RPC is not logged: %q", logged)
+		}
+		if strings.Contains(logged, "label-secret") {
+			t.Fatalf("label password is logged: %q", logged)
+		}
+		if payloads != strings.Contains(logged, "test-label") {
+			t.Fatalf("unexpected payloads logging %v: %q", payloads, logged)
+		}
+	}
+}
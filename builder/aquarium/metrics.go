@@ -0,0 +1,122 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the build telemetry, the builder reports each step and the whole build with it
+type Metrics interface {
+	// RecordDuration reports how long the step was running
+	RecordDuration(step string, d time.Duration)
+	// RecordOutcome reports the result of the step, err is nil on success
+	RecordOutcome(step string, err error)
+}
+
+// noopMetrics is used when the metrics are not enabled
+type noopMetrics struct{}
+
+func (noopMetrics) RecordDuration(string, time.Duration) {}
+func (noopMetrics) RecordOutcome(string, error)          {}
+
+// jsonMetrics writes the metrics as JSON lines
+type jsonMetrics struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// metricEvent is the JSON line written by jsonMetrics
+type metricEvent struct {
+	Time     time.Time `json:"time"`
+	Step     string    `json:"step"`
+	Duration float64   `json:"duration_seconds,omitempty"`
+	Outcome  string    `json:"outcome,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (m *jsonMetrics) RecordDuration(step string, d time.Duration) {
+	m.write(metricEvent{Time: time.Now(), Step: step, Duration: d.Seconds()})
+}
+
+func (m *jsonMetrics) RecordOutcome(step string, err error) {
+	event := metricEvent{Time: time.Now(), Step: step, Outcome: "success"}
+	if err != nil {
+		event.Outcome = "failure"
+		event.Error = err.Error()
+	}
+	m.write(event)
+}
+
+func (m *jsonMetrics) write(event metricEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fmt.Fprintln(m.w, string(data))
+}
+
+// statsdMetrics sends the metrics to StatsD over UDP, the failures to send are ignored
+type statsdMetrics struct {
+	conn   net.Conn
+	prefix string
+}
+
+func (m *statsdMetrics) RecordDuration(step string, d time.Duration) {
+	fmt.Fprintf(m.conn, "%s.%s.duration:%d|ms", m.prefix, step, d.Milliseconds())
+}
+
+func (m *statsdMetrics) RecordOutcome(step string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	fmt.Fprintf(m.conn, "%s.%s.%s:1|c", m.prefix, step, outcome)
+}
+
+// Close releases the UDP socket, the metrics can't be sent after it
+func (m *statsdMetrics) Close() error {
+	return m.conn.Close()
+}
+
+// newMetrics returns the reporter selected by AQUARIUM_METRICS: 1 or json writes JSON lines to
+// stdout, statsd sends them to AQUARIUM_METRICS_ENDPOINT (host:port), otherwise metrics are disabled
+func newMetrics() Metrics {
+	switch strings.ToLower(os.Getenv("AQUARIUM_METRICS")) {
+	case "1", "json":
+		return &jsonMetrics{w: os.Stdout}
+	case "statsd":
+		endpoint := os.Getenv("AQUARIUM_METRICS_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "localhost:8125"
+		}
+		conn, err := net.Dial("udp", endpoint)
+		if err != nil {
+			logInfo("Unable to connect to StatsD %s, metrics are disabled: %v", endpoint, err)
+			return noopMetrics{}
+		}
+		return &statsdMetrics{conn: conn, prefix: "packer.aquarium"}
+	}
+	return noopMetrics{}
+}
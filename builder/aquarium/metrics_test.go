@@ -0,0 +1,82 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// haltStep fails the build with the error
+type haltStep struct{}
+
+func (haltStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	state.Put("error", fmt.Errorf("no label found"))
+	return multistep.ActionHalt
+}
+
+func (haltStep) Cleanup(state multistep.StateBag) {}
+
+func TestMetrics_JSON(t *testing.T) {
+	var out bytes.Buffer
+	metrics := &jsonMetrics{w: &out}
+	state := new(multistep.BasicStateBag)
+	runner := &multistep.BasicRunner{Steps: trackPhases([]multistep.Step{&haltStep{}}, metrics)}
+	runner.Run(context.Background(), state)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected duration and outcome events, got: %q", out.String())
+	}
+	var duration, outcome metricEvent
+	if err := json.Unmarshal([]byte(lines[0]), &duration); err != nil {
+		t.Fatalf("unable to parse duration event: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &outcome); err != nil {
+		t.Fatalf("unable to parse outcome event: %v", err)
+	}
+	if duration.Step != "haltStep" || outcome.Step != "haltStep" {
+		t.Fatalf("unexpected step names: %q, %q", duration.Step, outcome.Step)
+	}
+	if outcome.Outcome != "failure" || outcome.Error != "no label found" {
+		t.Fatalf("unexpected outcome: %+v", outcome)
+	}
+}
+
+func TestNewMetrics(t *testing.T) {
+	t.Setenv("AQUARIUM_METRICS", "")
+	if _, ok := newMetrics().(noopMetrics); !ok {
+		t.Fatalf("metrics should be disabled by default")
+	}
+	t.Setenv("AQUARIUM_METRICS", "1")
+	if _, ok := newMetrics().(*jsonMetrics); !ok {
+		t.Fatalf("AQUARIUM_METRICS=1 should enable JSON metrics")
+	}
+	t.Setenv("AQUARIUM_METRICS", "statsd")
+	t.Setenv("AQUARIUM_METRICS_ENDPOINT", "127.0.0.1:8125")
+	metrics, ok := newMetrics().(*statsdMetrics)
+	if !ok {
+		t.Fatalf("AQUARIUM_METRICS=statsd should enable StatsD metrics")
+	}
+	if err := metrics.Close(); err != nil {
+		t.Fatalf("unable to close StatsD connection: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// provisionLogHook writes the provisioners output to provision_log_path in addition to the UI, the
+// provisioners are receiving the UI from the hook so it's replaced here for the provisioning phase
+type provisionLogHook struct {
+	packer.Hook
+	path string
+}
+
+// Run executes the wrapped hook with the UI copying the output to the log file
+func (h *provisionLogHook) Run(ctx context.Context, name string, ui packer.Ui, comm packer.Communicator, data any) error {
+	if name != packer.HookProvision && name != packer.HookCleanupProvision {
+		return h.Hook.Run(ctx, name, ui, comm, data)
+	}
+	// Appending, so the cleanup provisioner output is kept after the main one
+	logFile, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to open provision log %s: %v", h.path, err)
+	}
+	defer logFile.Close()
+
+	return h.Hook.Run(ctx, name, &teeUi{Ui: ui, out: logFile}, comm, data)
+}
+
+// teeUi duplicates the UI messages to the writer with timestamps
+type teeUi struct {
+	packer.Ui
+
+	// Provisioners could write stdout and stderr concurrently
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (u *teeUi) Say(message string) {
+	u.Ui.Say(message)
+	u.write("", message)
+}
+
+func (u *teeUi) Sayf(format string, args ...any) {
+	u.Say(fmt.Sprintf(format, args...))
+}
+
+func (u *teeUi) Message(message string) {
+	u.Ui.Message(message)
+	u.write("", message)
+}
+
+func (u *teeUi) Error(message string) {
+	u.Ui.Error(message)
+	u.write("ERROR: ", message)
+}
+
+func (u *teeUi) Errorf(format string, args ...any) {
+	u.Error(fmt.Sprintf(format, args...))
+}
+
+// write appends the message to the log, the log failures are not breaking the provisioning
+func (u *teeUi) write(prefix, message string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, err := fmt.Fprintf(u.out, "%s %s%s\n", time.Now().UTC().Format(time.RFC3339), prefix, message); err != nil {
+		logInfo("Unable to write the provision log: %v", err)
+	}
+}
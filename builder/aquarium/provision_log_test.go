@@ -0,0 +1,62 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestProvisionLogHook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provision.log")
+	mock := &packersdk.MockHook{}
+	mock.RunFunc = func(ctx context.Context) error {
+		mock.RunUi.Say("Provisioning with shell script")
+		mock.RunUi.Message("installing packages")
+		mock.RunUi.Errorf("exit code %d", 1)
+		return nil
+	}
+	hook := &provisionLogHook{Hook: mock, path: path}
+
+	ui := &packersdk.MockUi{}
+	for _, name := range []string{packersdk.HookProvision, packersdk.HookCleanupProvision, "other"} {
+		if err := hook.Run(context.Background(), name, ui, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(ui.SayMessages) != 3 || ui.ErrorMessage != "exit code 1" {
+		t.Fatalf("messages are not passed to the UI: %v, %q", ui.SayMessages, ui.ErrorMessage)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read provision log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// The other hooks are not logged
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines in provision log, got %d: %s", len(lines), data)
+	}
+	for i, suffix := range []string{" Provisioning with shell script", " installing packages", " ERROR: exit code 1"} {
+		if !strings.HasSuffix(lines[i], suffix) || !strings.HasSuffix(lines[i+3], suffix) {
+			t.Errorf("line %d: expected suffix %q, got %q", i, suffix, lines[i])
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	connect "connectrpc.com/connect"
+)
+
+// maxRetryBackoff limits the exponential growth of the delay between the retries
+const maxRetryBackoff = 30 * time.Second
+
+// callerManagedKey marks the context to skip the automatic retries and request timeout
+type callerManagedKey struct{}
+
+// callerManaged disables the automatic retries and request timeout for the calls made with the
+// returned context, is used when the caller handles the retries and deadlines itself
+func callerManaged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, callerManagedKey{}, true)
+}
+
+// isIdempotentProcedure checks if the RPC could be repeated safely. The Create* calls are not, since
+// the request timed out on the client side could still create the object in Fish and the extra
+// application holds the resources without anyone to deallocate it
+func isIdempotentProcedure(procedure string) bool {
+	method := procedure[strings.LastIndex(procedure, "/")+1:]
+	return strings.HasPrefix(method, "Get") || strings.HasPrefix(method, "List") || method == "Deallocate"
+}
+
+// newRetryInterceptor retries the idempotent unary RPC calls failed with transient errors using
+// exponential backoff starting from the provided base duration
+func newRetryInterceptor(retries int, backoff time.Duration) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if ctx.Value(callerManagedKey{}) != nil || !isIdempotentProcedure(req.Spec().Procedure) {
+				return next(ctx, req)
+			}
+			delay := backoff
+			for attempt := 0; ; attempt++ {
+				resp, err := next(ctx, req)
+				if err == nil || attempt >= retries || !isTransientError(ctx, err) {
+					return resp, err
+				}
+				logInfo("RPC %s failed with transient error: %v, retrying in %s (%d/%d)",
+					req.Spec().Procedure, err, delay, attempt+1, retries)
+
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(delay):
+				}
+				delay = min(delay*2, maxRetryBackoff)
+			}
+		}
+	}
+}
+
+// isTransientError checks if the call could succeed when repeated, the permission or not found
+// errors are not going to change so not retried
+func isTransientError(ctx context.Context, err error) bool {
+	// Caller context is done, so no reason to repeat
+	if ctx.Err() != nil {
+		return false
+	}
+	// Connect reports the network errors (connection reset/refused) as Unavailable
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeDeadlineExceeded, connect.CodeAborted, connect.CodeResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// newTimeoutInterceptor sets the deadline for the unary RPC calls, so the hung call will not
+// block the caller forever
+func newTimeoutInterceptor(timeout time.Duration) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if ctx.Value(callerManagedKey{}) != nil {
+				return next(ctx, req)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, req)
+		}
+	}
+}
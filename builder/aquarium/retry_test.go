@@ -0,0 +1,85 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+)
+
+// unavailableApplicationService fails all the calls as unavailable and counts them
+type unavailableApplicationService struct {
+	aquariumv2connect.UnimplementedApplicationServiceHandler
+	calls map[string]int
+}
+
+func (s *unavailableApplicationService) List(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceListRequest]) (*connect.Response[aquariumv2.ApplicationServiceListResponse], error) {
+	s.calls["List"]++
+	return nil, connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+}
+
+func (s *unavailableApplicationService) Create(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceCreateRequest]) (*connect.Response[aquariumv2.ApplicationServiceCreateResponse], error) {
+	s.calls["Create"]++
+	return nil, connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+}
+
+func TestRetryInterceptor_Idempotent(t *testing.T) {
+	apps := &unavailableApplicationService{calls: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	client := NewAPIClient(server.URL, APIAuth{Username: "admin", Password: "admin"}, server.Client(),
+		WithRetry(2, time.Millisecond))
+
+	if _, err := client.ListApplications(context.Background(), nil); err == nil {
+		t.Fatalf("expected list error")
+	}
+	if _, err := client.CreateApplication(context.Background(), &aquariumv2.Application{}); err == nil {
+		t.Fatalf("expected create error")
+	}
+	if apps.calls["List"] != 3 {
+		t.Errorf("expected List to be retried 2 times, got %d calls", apps.calls["List"])
+	}
+	if apps.calls["Create"] != 1 {
+		t.Errorf("expected Create to not be retried, got %d calls", apps.calls["Create"])
+	}
+}
+
+func TestIsIdempotentProcedure(t *testing.T) {
+	tests := map[string]bool{
+		aquariumv2connect.ApplicationServiceListProcedure:       true,
+		aquariumv2connect.ApplicationServiceGetStateProcedure:   true,
+		aquariumv2connect.ApplicationServiceDeallocateProcedure: true,
+		aquariumv2connect.UserServiceGetMeProcedure:             true,
+		aquariumv2connect.ApplicationServiceCreateProcedure:     false,
+		aquariumv2connect.ApplicationServiceCreateTaskProcedure: false,
+		aquariumv2connect.LabelServiceCreateProcedure:           false,
+		aquariumv2connect.LabelServiceRemoveProcedure:           false,
+	}
+	for procedure, idempotent := range tests {
+		if isIdempotentProcedure(procedure) != idempotent {
+			t.Errorf("isIdempotentProcedure(%s) expected %v", procedure, idempotent)
+		}
+	}
+}
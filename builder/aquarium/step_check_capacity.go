@@ -0,0 +1,98 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCheckCapacity makes sure the cluster has a node able to fit the label resources before
+// creating the application, otherwise it will wait in the queue until allocation timeout
+type StepCheckCapacity struct {
+	Config *Config
+}
+
+// Run executes the step to check the cluster capacity
+func (s *StepCheckCapacity) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	client := state.Get("api_client").(*APIClient)
+	label := state.Get("selected_label").(*aquariumv2.Label)
+
+	ui.Say("Checking cluster capacity...")
+
+	// It's just a preflight check, so the build is not failed when the nodes are not available
+	capacity, err := client.GetClusterCapacity(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return haltCancelled(ctx, ui, state, "capacity check")
+		}
+		ui.Message(fmt.Sprintf("Unable to get cluster capacity, skipping the check: %v", err))
+		return multistep.ActionContinue
+	}
+	if len(capacity.Nodes) == 0 {
+		ui.Message("No cluster nodes are visible to the user, skipping the check")
+		return multistep.ActionContinue
+	}
+
+	// Fish picks the definition by itself unless definition_index is set
+	definitions := label.GetDefinitions()
+	if index := s.Config.DefinitionIndex; index != nil {
+		definitions = definitions[*index : *index+1]
+	}
+	for _, def := range definitions {
+		if node, ok := capacity.fit(def.GetResources()); ok {
+			ui.Say(fmt.Sprintf("Node %s is able to fit the label resources", node.Name))
+			return multistep.ActionContinue
+		}
+	}
+
+	largest := capacity.largest()
+	ui.Error(fmt.Sprintf("None of %d cluster node(s) can fit label '%s' resources, the largest node %s has %d CPU and %dGB RAM: "+
+		"set skip_capacity_check if the nodes are overbooking resources",
+		len(capacity.Nodes), label.GetName(), largest.Name, largest.CPU, largest.RAM))
+	state.Put("error", fmt.Errorf("insufficient cluster capacity for label %s", label.GetName()))
+	return multistep.ActionHalt
+}
+
+// fit returns the first node having enough CPU and RAM for the resources
+func (c *ClusterCapacity) fit(resources *aquariumv2.Resources) (NodeCapacity, bool) {
+	for _, node := range c.Nodes {
+		if node.CPU >= resources.GetCpu() && node.RAM >= resources.GetRam() {
+			return node, true
+		}
+	}
+	return NodeCapacity{}, false
+}
+
+// largest returns the node with the most CPU and RAM to show in the error message
+func (c *ClusterCapacity) largest() NodeCapacity {
+	var largest NodeCapacity
+	for _, node := range c.Nodes {
+		if node.CPU > largest.CPU || (node.CPU == largest.CPU && node.RAM > largest.RAM) {
+			largest = node
+		}
+	}
+	return largest
+}
+
+// Cleanup performs any necessary cleanup
+func (s *StepCheckCapacity) Cleanup(state multistep.StateBag) {
+	// Nothing to clean up for the capacity check
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+type testNodeService struct {
+	aquariumv2connect.UnimplementedNodeServiceHandler
+	nodes []*aquariumv2.Node
+}
+
+func (s *testNodeService) List(ctx context.Context, req *connect.Request[aquariumv2.NodeServiceListRequest]) (*connect.Response[aquariumv2.NodeServiceListResponse], error) {
+	return connect.NewResponse(&aquariumv2.NodeServiceListResponse{Status: true, Data: s.nodes}), nil
+}
+
+// newTestNode creates the node with linux-like CPU info reported per core
+func newTestNode(name string, cpu int, ramGB uint64) *aquariumv2.Node {
+	def := &aquariumv2.NodeDefinition{Memory: &aquariumv2.MemoryInfo{Total: ramGB << 30}}
+	for i := 0; i < cpu; i++ {
+		def.Cpu = append(def.Cpu, &aquariumv2.CpuInfo{Cores: 1})
+	}
+	return &aquariumv2.Node{Uid: name + "-uid", Name: name, Definition: def}
+}
+
+func TestStepCheckCapacity(t *testing.T) {
+	label := newTestLabel("label-v1", 1)
+	label.Definitions = []*aquariumv2.LabelDefinition{
+		{Driver: "large", Resources: &aquariumv2.Resources{Cpu: 16, Ram: 64}},
+		{Driver: "small", Resources: &aquariumv2.Resources{Cpu: 4, Ram: 8}},
+	}
+
+	first, second := 0, 1
+	for _, tt := range []struct {
+		name            string
+		nodes           []*aquariumv2.Node
+		definitionIndex *int
+		action          multistep.StepAction
+	}{
+		{"fits any definition", []*aquariumv2.Node{newTestNode("node-1", 8, 16)}, nil, multistep.ActionContinue},
+		{"fits selected definition", []*aquariumv2.Node{newTestNode("node-1", 8, 16)}, &second, multistep.ActionContinue},
+		{"selected first definition", []*aquariumv2.Node{newTestNode("node-1", 8, 16)}, &first, multistep.ActionHalt},
+		{"not enough cpu", []*aquariumv2.Node{newTestNode("node-1", 2, 16)}, nil, multistep.ActionHalt},
+		{"not enough ram", []*aquariumv2.Node{newTestNode("node-1", 8, 4), newTestNode("node-2", 2, 128)}, nil, multistep.ActionHalt},
+		{"no nodes visible", nil, nil, multistep.ActionContinue},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestFish(t, func(mux *http.ServeMux) {
+				mux.Handle(aquariumv2connect.NewNodeServiceHandler(&testNodeService{nodes: tt.nodes}))
+			})
+			state := newTestState(t, client)
+			state.Put("selected_label", label)
+
+			step := &StepCheckCapacity{Config: &Config{DefinitionIndex: tt.definitionIndex}}
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %v, got %v: %v", tt.action, action, state.Get("error"))
+			}
+		})
+	}
+}
+
+func TestStepCheckCapacity_NodesUnavailable(t *testing.T) {
+	// Node service is not registered, so the check is skipped
+	client := newTestFish(t, func(mux *http.ServeMux) {})
+	state := newTestState(t, client)
+	state.Put("selected_label", newTestLabel("label-v1", 1))
+
+	step := &StepCheckCapacity{Config: &Config{}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected to continue, got %v: %v", action, state.Get("error"))
+	}
+}
@@ -17,7 +17,6 @@ package aquarium
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
@@ -27,8 +26,7 @@ import (
 
 // StepCleanup handles cleanup of AquariumFish resources
 type StepCleanup struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
 // Run executes the cleanup step
@@ -47,6 +45,8 @@ func (s *StepCleanup) Cleanup(state multistep.StateBag) {
 		return
 	}
 	apiClient := client.(*APIClient)
+	// The label is removed when the application is not using it anymore
+	defer s.removeCreatedLabel(ui, state, apiClient)
 
 	// Get the application if available
 	app, hasApp := state.GetOk("application")
@@ -56,10 +56,19 @@ func (s *StepCleanup) Cleanup(state multistep.StateBag) {
 	}
 	application := app.(*aquariumv2.Application)
 
+	if s.keepResource(state) {
+		s.reportKeptResource(ui, state, application)
+		return
+	}
+
 	ui.Say("Cleaning up AquariumFish resources...")
 
+	// The build context could be already cancelled here, so using a fresh one limited by cleanup timeout
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), s.Config.deallocationTimeoutDuration)
+	defer cancel()
+
 	// Trigger application deallocation
-	err := apiClient.DeallocateApplication(context.Background(), application.GetUid())
+	err := apiClient.DeallocateApplication(timeoutCtx, application.GetUid())
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to deallocate application: %v", err))
 		// Don't halt on cleanup errors, just log them
@@ -68,26 +77,26 @@ func (s *StepCleanup) Cleanup(state multistep.StateBag) {
 
 	ui.Say(fmt.Sprintf("Application %s deallocate request sent...", application.GetUid()))
 
-	// Wait a bit to ensure deallocation starts
-	time.Sleep(5 * time.Second)
+	if s.Config.WaitForDeallocation.False() {
+		ui.Say("wait_for_deallocation is disabled, not waiting for deallocation to complete")
+		return
+	}
 
-	// Optionally wait for deallocation to complete
+	// Optionally wait for deallocation to complete, the first check happens after the poll interval
 	ui.Say("Waiting for deallocation to complete...")
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
 
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(s.Config.deallocationPollIntervalDuration)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-timeoutCtx.Done():
-			ui.Say("Deallocation timeout reached, but continuing...")
+			ui.Say(fmt.Sprintf("Deallocation timeout reached (%s), but continuing...", s.Config.DeallocationTimeout))
 			return
 
 		case <-ticker.C:
 			// Check application state
-			appState, err := apiClient.GetApplicationState(context.Background(), application.GetUid())
+			appState, err := apiClient.GetApplicationState(timeoutCtx, application.GetUid())
 			if err != nil {
 				ui.Say(fmt.Sprintf("Could not check application state: %v", err))
 				return
@@ -95,17 +104,71 @@ func (s *StepCleanup) Cleanup(state multistep.StateBag) {
 
 			ui.Say(fmt.Sprintf("Application status: %s", appState.GetStatus().String()))
 
-			if appState.GetStatus() == aquariumv2.ApplicationState_DEALLOCATED || appState.GetStatus() == aquariumv2.ApplicationState_DEALLOCATE {
+			switch appState.GetStatus() {
+			case aquariumv2.ApplicationState_DEALLOCATED:
 				ui.Say("Application successfully deallocated")
 				return
-			}
 
-			if appState.GetStatus() == aquariumv2.ApplicationState_ERROR {
+			case aquariumv2.ApplicationState_ERROR:
 				ui.Say(fmt.Sprintf("Application in error state during deallocation: %s", appState.GetDescription()))
 				return
-			}
 
-			// Continue waiting for other statuses
+			default:
+				// DEALLOCATE means the deallocation is still in progress, continue waiting
+				continue
+			}
 		}
 	}
 }
+
+// removeCreatedLabel removes the label created by StepCreateLabel if cleanup_label is set
+func (s *StepCleanup) removeCreatedLabel(ui packersdk.Ui, state multistep.StateBag, client *APIClient) {
+	raw, ok := state.GetOk("created_label")
+	if !ok || !s.Config.CleanupLabel {
+		return
+	}
+	label := raw.(*aquariumv2.Label)
+	if s.keepResource(state) {
+		ui.Say(fmt.Sprintf("Keeping label %s used by the kept application", label.GetUid()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.deallocationTimeoutDuration)
+	defer cancel()
+	if err := client.DeleteLabel(ctx, label.GetUid()); err != nil {
+		ui.Error(fmt.Sprintf("Failed to remove label %s: %v", label.GetUid(), err))
+		return
+	}
+	ui.Say(fmt.Sprintf("Label '%s' version %d removed", label.GetName(), label.GetVersion()))
+}
+
+// keepResource checks if the application should be left allocated after the build
+func (s *StepCleanup) keepResource(state multistep.StateBag) bool {
+	if _, hasError := state.GetOk("error"); hasError {
+		return s.Config.KeepResourceOnError
+	}
+	return s.Config.DeallocateOnSuccess.False()
+}
+
+// reportKeptResource prints the details to access the resource left alive for debugging or the
+// follow-up processes
+func (s *StepCleanup) reportKeptResource(ui packersdk.Ui, state multistep.StateBag, application *aquariumv2.Application) {
+	if _, hasError := state.GetOk("error"); hasError {
+		ui.Say(fmt.Sprintf("Build failed and keep_resource_on_error is set, keeping application %s allocated", application.GetUid()))
+	} else {
+		ui.Say(fmt.Sprintf("Build succeeded and deallocate_on_success is disabled, keeping application %s allocated", application.GetUid()))
+	}
+	if raw, ok := state.GetOk("application_resource"); ok {
+		ui.Say(fmt.Sprintf("Application resource UID: %s", raw.(*aquariumv2.ApplicationResource).GetUid()))
+	}
+
+	if sshHost, ok := state.GetOk("ssh_host"); ok {
+		sshPort, _ := state.GetOk("ssh_port")
+		sshUsername, _ := state.GetOk("ssh_username")
+		ui.Say(fmt.Sprintf("You can connect to the Resource by: ssh -p %v %v@%v", sshPort, sshUsername, sshHost))
+	} else {
+		ui.Say("SSH access was not set up yet, so no connection details are available")
+	}
+
+	ui.Say(fmt.Sprintf("Don't forget to deallocate the application %s when it's not needed anymore", application.GetUid()))
+}
@@ -0,0 +1,92 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCleanupOrphans deallocates the applications left by the previous crashed builds
+//
+// Only the applications of the current user created by this builder (packer_builder=aquarium) for
+// the same build name (packer_build_name) and older than cleanup_orphans_older_than are
+// deallocated, so the threshold should be greater than the longest build to not affect the running ones
+type StepCleanupOrphans struct {
+	Config *Config
+}
+
+// Run executes the step to deallocate orphaned applications
+func (s *StepCleanupOrphans) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Config.cleanupOrphansOlderThanDuration <= 0 {
+		return multistep.ActionContinue
+	}
+	ui := state.Get("ui").(packersdk.Ui)
+	client := state.Get("api_client").(*APIClient)
+
+	ui.Say(fmt.Sprintf("Looking for orphaned applications older than %s...", s.Config.CleanupOrphansOlderThan))
+
+	user, err := client.GetCurrentUser(ctx)
+	if err != nil {
+		// Cleanup of the orphans is not critical for the build
+		ui.Error(fmt.Sprintf("Failed to get current user, skipping orphans cleanup: %v", err))
+		return multistep.ActionContinue
+	}
+
+	threshold := time.Now().Add(-s.Config.cleanupOrphansOlderThanDuration)
+	apps, err := client.ListApplications(ctx, func(app *aquariumv2.Application) bool {
+		metadata := app.GetMetadata().GetFields()
+		return app.GetOwnerName() == user.GetName() &&
+			metadata[s.Config.metadataKey(metadataKeyBuilder)].GetStringValue() == "aquarium" &&
+			metadata[s.Config.metadataKey(metadataKeyBuildName)].GetStringValue() == s.Config.PackerBuildName &&
+			metadata[s.Config.metadataKey(metadataKeyBuildID)].GetStringValue() != s.Config.buildID &&
+			app.GetCreatedAt().AsTime().Before(threshold)
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to list applications, skipping orphans cleanup: %v", err))
+		return multistep.ActionContinue
+	}
+
+	for _, app := range apps {
+		appState, err := client.GetApplicationState(ctx, app.GetUid())
+		if err != nil {
+			ui.Message(fmt.Sprintf("Unable to get application %s state: %v", app.GetUid(), err))
+			continue
+		}
+		switch appState.GetStatus() {
+		case aquariumv2.ApplicationState_DEALLOCATE, aquariumv2.ApplicationState_DEALLOCATED, aquariumv2.ApplicationState_ERROR:
+			// Already deallocated or deallocating
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Deallocating orphaned application %s created at %s",
+			app.GetUid(), app.GetCreatedAt().AsTime().Format(time.RFC3339)))
+		if err := client.DeallocateApplication(ctx, app.GetUid()); err != nil {
+			ui.Error(fmt.Sprintf("Failed to deallocate orphaned application %s: %v", app.GetUid(), err))
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup performs any necessary cleanup
+func (s *StepCleanupOrphans) Cleanup(state multistep.StateBag) {
+	// Nothing to clean up for orphans cleanup
+}
@@ -0,0 +1,136 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+func newTestCleanup(t *testing.T) (*StepCleanup, *multistep.BasicStateBag, *testApplicationService) {
+	service := &testApplicationService{}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(service))
+	})
+	step := &StepCleanup{Config: &Config{
+		WaitForDeallocation:         config.TriFalse,
+		deallocationTimeoutDuration: time.Minute,
+	}}
+	state := newTestState(t, client)
+	state.Put("application", &aquariumv2.Application{Uid: "app-uid"})
+	return step, state, service
+}
+
+func TestStepCleanup_Deallocates(t *testing.T) {
+	step, state, service := newTestCleanup(t)
+
+	step.Cleanup(state)
+
+	if len(service.deallocated) != 1 || service.deallocated[0] != "app-uid" {
+		t.Fatalf("unexpected deallocated applications: %v", service.deallocated)
+	}
+	if !service.hasDeadline {
+		t.Fatalf("deallocation request context has no deadline")
+	}
+}
+
+func TestStepCleanup_CancelledBuild(t *testing.T) {
+	step, state, service := newTestCleanup(t)
+	state.Put("error", fmt.Errorf("build cancelled"))
+
+	// The build is cancelled while waiting, the runner cleans up with the context already done
+	ctx, cancel := context.WithCancel(context.Background())
+	wait := &waitStep{}
+	runner := &multistep.BasicRunner{Steps: []multistep.Step{step, wait}}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	runner.Run(ctx, state)
+
+	if ctx.Err() == nil || !wait.cleaned {
+		t.Fatalf("build was not cancelled")
+	}
+	if len(service.deallocated) != 1 || service.deallocated[0] != "app-uid" {
+		t.Fatalf("application was not deallocated after cancellation: %v", service.deallocated)
+	}
+	if !service.hasDeadline {
+		t.Fatalf("deallocation request context has no deadline")
+	}
+}
+
+func TestStepCleanup_KeepResourceOnError(t *testing.T) {
+	step, state, service := newTestCleanup(t)
+	step.Config.KeepResourceOnError = true
+	state.Put("error", fmt.Errorf("provisioning failed"))
+
+	step.Cleanup(state)
+
+	if len(service.deallocated) != 0 {
+		t.Fatalf("application was deallocated: %v", service.deallocated)
+	}
+}
+
+func TestStepCleanup_DeallocateOnSuccess(t *testing.T) {
+	step, state, service := newTestCleanup(t)
+	step.Config.DeallocateOnSuccess = config.TriFalse
+
+	step.Cleanup(state)
+	if len(service.deallocated) != 0 {
+		t.Fatalf("application was deallocated after success: %v", service.deallocated)
+	}
+
+	// The failed build resources are deallocated unless keep_resource_on_error is set
+	state.Put("error", fmt.Errorf("provisioning failed"))
+	step.Cleanup(state)
+	if len(service.deallocated) != 1 {
+		t.Fatalf("application was not deallocated after failure: %v", service.deallocated)
+	}
+}
+
+func TestStepCleanup_WaitsForTerminalState(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []aquariumv2.ApplicationState_Status
+		calls    int
+	}{
+		{"deallocated", []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_DEALLOCATED}, 1},
+		{"error", []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_ERROR}, 1},
+		{"deallocate in progress", []aquariumv2.ApplicationState_Status{
+			aquariumv2.ApplicationState_DEALLOCATE,
+			aquariumv2.ApplicationState_DEALLOCATE,
+			aquariumv2.ApplicationState_DEALLOCATED,
+		}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, state, service := newTestCleanup(t)
+			step.Config.WaitForDeallocation = config.TriTrue
+			step.Config.deallocationPollIntervalDuration = time.Millisecond
+			service.statuses = tt.statuses
+
+			step.Cleanup(state)
+
+			if service.stateCalls != tt.calls {
+				t.Fatalf("expected %d state checks, got %d", tt.calls, service.stateCalls)
+			}
+		})
+	}
+}
@@ -18,14 +18,19 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	connect "connectrpc.com/connect"
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// connectionRetryDelay is the pause between the API connectivity check attempts
+const connectionRetryDelay = 5 * time.Second
+
 // StepConnectAPI connects to the AquariumFish API and verifies authentication
 type StepConnectAPI struct {
 	Config     *Config
@@ -39,19 +44,44 @@ func (s *StepConnectAPI) Run(ctx context.Context, state multistep.StateBag) mult
 	ui.Say("Connecting to AquariumFish API...")
 
 	// Create API client
-	endpointURL, _ := url.Parse(s.Config.Endpoint)
-	if endpointURL.Path == "" {
-		// Setting "grpc" if the path is empty
-		endpointURL.Path = "grpc"
+	client, err := configuredAPIClient(s.Config, s.HTTPClient)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to create AquariumFish API client: %v", err))
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if s.Config.apiVersion != nil {
+		ui.Say(fmt.Sprintf("Targeting Fish API version %s, the plugin API version is %s", s.Config.apiVersion, FishAPIVersion()))
 	}
-	client := NewAPIClient(endpointURL.String(), s.Config.Username, s.Config.Password, s.HTTPClient)
 
-	// Test the connection by getting the current user info
-	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-	if _, err := client.GetCurrentUser(ctxTimeout); err != nil {
+	// Make sure the OAuth2 token could be received before talking to the API
+	if err := client.EnsureToken(); err != nil {
+		ui.Error(fmt.Sprintf("Failed to obtain OAuth2 token from %s: %v", s.Config.OAuthTokenURL, err))
+		state.Put("error", fmt.Errorf("OAuth2 token request failed: %v", err))
+		return multistep.ActionHalt
+	}
+
+	// Test the connection by getting the current user info, the endpoint could still be coming up
+	// so retrying it connection_retries times with connection_timeout for each attempt
+	for attempt := 1; attempt <= s.Config.ConnectionRetries; attempt++ {
+		attemptCtx, attemptCancel := context.WithTimeout(callerManaged(ctx), s.Config.connectionTimeoutDuration)
+		_, err = client.GetCurrentUser(attemptCtx)
+		attemptCancel()
+		if err == nil || attempt == s.Config.ConnectionRetries || !isTransientError(ctx, err) {
+			break
+		}
+
+		ui.Say(fmt.Sprintf("Connection attempt %d/%d failed: %v, retrying in %s...",
+			attempt, s.Config.ConnectionRetries, err, connectionRetryDelay))
+		select {
+		case <-ctx.Done():
+		case <-time.After(connectionRetryDelay):
+		}
+	}
+	if err != nil {
+		err = connectionError(s.Config.Endpoint, err)
 		ui.Error(fmt.Sprintf("Failed to connect to AquariumFish API: %v", err))
-		state.Put("error", fmt.Errorf("API connection failed: %v", err))
+		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
@@ -60,6 +90,14 @@ func (s *StepConnectAPI) Run(ctx context.Context, state multistep.StateBag) mult
 	// Store the API client in state for other steps
 	state.Put("api_client", client)
 
+	if s.Config.ResumeApplicationUID != "" {
+		if err := s.resumeApplication(ctx, ui, state, client); err != nil {
+			ui.Error(fmt.Sprintf("Unable to resume application %s: %v", s.Config.ResumeApplicationUID, err))
+			state.Put("error", fmt.Errorf("resume application %s: %v", s.Config.ResumeApplicationUID, err))
+			return multistep.ActionHalt
+		}
+	}
+
 	// Create subscription stream for updates used by later steps
 	// Subscribe to objects we care about during build
 	subTypes := []aquariumv2.SubscriptionType{
@@ -69,14 +107,116 @@ func (s *StepConnectAPI) Run(ctx context.Context, state multistep.StateBag) mult
 		aquariumv2.SubscriptionType_SUBSCRIPTION_TYPE_APPLICATION_TASK,
 	}
 	stream, err := client.Subscribe(ctx, subTypes)
-	if err == nil {
-		state.Put("subscribe_stream", stream)
+	if err != nil {
+		// The wait steps are polling the API, so the build could continue without the updates stream
+		ui.Message(fmt.Sprintf("Unable to subscribe to the updates, falling back to polling: %v", err))
+		if isStreamBlocked(err) && s.Config.Protocol != ProtocolGRPCWeb {
+			ui.Message(fmt.Sprintf("The streaming responses look blocked by a proxy, consider setting protocol to %q", ProtocolGRPCWeb))
+		}
+	} else {
+		state.Put("subscribe_stream", subscriptionStream(stream))
 	}
 
 	return multistep.ActionContinue
 }
 
-// Cleanup performs any necessary cleanup
+// resumeApplication loads the allocated application, its resource and label into the state as they
+// would be set by the skipped label lookup, creation and allocation steps
+func (s *StepConnectAPI) resumeApplication(ctx context.Context, ui packersdk.Ui, state multistep.StateBag, client *APIClient) error {
+	uid := s.Config.ResumeApplicationUID
+	ui.Say(fmt.Sprintf("Resuming the build with application %s...", uid))
+
+	application, err := client.GetApplication(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("unable to get application: %v", err)
+	}
+	appState, err := client.GetApplicationState(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("unable to get application state: %v", err)
+	}
+	if appState.GetStatus() != aquariumv2.ApplicationState_ALLOCATED {
+		return fmt.Errorf("application should be ALLOCATED, but it's %s: %s", appState.GetStatus(), appState.GetDescription())
+	}
+	resource, err := client.GetApplicationResource(ctx, uid)
+	if err != nil || resource == nil {
+		return fmt.Errorf("unable to get application resource: %v", err)
+	}
+	label, err := client.GetLabel(ctx, application.GetLabelUid())
+	if err != nil {
+		return fmt.Errorf("unable to get application label %s: %v", application.GetLabelUid(), err)
+	}
+
+	ui.Say(fmt.Sprintf("Resumed application %s with resource %s (IP: %s) from label '%s' version %d",
+		uid, resource.GetUid(), resource.GetIpAddr(), label.GetName(), label.GetVersion()))
+
+	state.Put("selected_label", label)
+	state.Put("application", application)
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["LabelName"] = label.GetName()
+	generatedData["LabelVersion"] = strconv.Itoa(int(label.GetVersion()))
+	generatedData["ApplicationUID"] = uid
+	state.Put("generated_data", generatedData)
+	storeApplicationResource(state, resource)
+	return nil
+}
+
+// connectionError separates the credentials problems from the network ones, since the expired token
+// or disabled account could look like the endpoint failure otherwise
+func connectionError(endpoint string, err error) error {
+	switch connect.CodeOf(err) {
+	case connect.CodeUnauthenticated:
+		return fmt.Errorf("authentication failed: check credentials: %v", err)
+	case connect.CodePermissionDenied:
+		return fmt.Errorf("authentication failed: the account is disabled or has no access: %v", err)
+	case connect.CodeUnavailable, connect.CodeDeadlineExceeded:
+		return fmt.Errorf("cannot reach endpoint %s: %v", endpoint, err)
+	}
+	return fmt.Errorf("API connection failed: %v", err)
+}
+
+// streamBlockedMarkers are the parts of the errors returned when the proxy terminates HTTP/2 server
+// streams or doesn't pass the trailers
+var streamBlockedMarkers = []string{
+	"http2",
+	"protocol error",
+	"stream error",
+	"rst_stream",
+	"trailer",
+	"unexpected eof",
+	"unexpected content-type",
+}
+
+// isStreamBlocked checks if the stream failure looks like proxy incompatibility
+func isStreamBlocked(err error) bool {
+	switch connect.CodeOf(err) {
+	case connect.CodeUnknown, connect.CodeInternal, connect.CodeUnimplemented, connect.CodeUnavailable:
+	default:
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, marker := range streamBlockedMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cleanup closes the subscription stream to release it on the server side
 func (s *StepConnectAPI) Cleanup(state multistep.StateBag) {
-	// Nothing to clean up for API connection
+	// Stream is absent when Subscribe failed
+	raw, ok := state.GetOk("subscribe_stream")
+	if !ok {
+		return
+	}
+	state.Remove("subscribe_stream")
+
+	ui := state.Get("ui").(packersdk.Ui)
+	stream := raw.(subscriptionStream)
+	if reconnects := stream.Reconnects(); reconnects > 0 {
+		ui.Say(fmt.Sprintf("Subscription stream was reconnected %d times", reconnects))
+	}
+	if err := stream.Close(); err != nil {
+		ui.Say(fmt.Sprintf("Failed to close the subscription stream: %v", err))
+	}
 }
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+type testStream struct {
+	closed int
+}
+
+func (s *testStream) Receive() (*aquariumv2.StreamingServiceSubscribeResponse, error) {
+	return nil, io.EOF
+}
+
+func (s *testStream) Reconnects() int { return 0 }
+
+func (s *testStream) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestStepConnectAPI_CleanupClosesStream(t *testing.T) {
+	stream := &testStream{}
+	state := newTestState(t, nil)
+	state.Put("subscribe_stream", subscriptionStream(stream))
+
+	step := &StepConnectAPI{Config: &Config{}}
+	step.Cleanup(state)
+	step.Cleanup(state)
+
+	if stream.closed != 1 {
+		t.Fatalf("expected the stream to be closed once, got %d", stream.closed)
+	}
+}
+
+func TestStepConnectAPI_CleanupWithoutStream(t *testing.T) {
+	state := newTestState(t, nil)
+
+	step := &StepConnectAPI{Config: &Config{}}
+	step.Cleanup(state)
+}
+
+func TestIsStreamBlocked(t *testing.T) {
+	tests := []struct {
+		err     error
+		blocked bool
+	}{
+		{connect.NewError(connect.CodeInternal, errors.New("stream error: stream ID 1; PROTOCOL_ERROR")), true},
+		{connect.NewError(connect.CodeUnknown, errors.New("http2: server sent GOAWAY and closed the connection")), true},
+		{connect.NewError(connect.CodeUnknown, errors.New("unexpected EOF")), true},
+		{connect.NewError(connect.CodePermissionDenied, errors.New("stream error")), false},
+		{connect.NewError(connect.CodeUnavailable, errors.New("connection refused")), false},
+	}
+	for _, tt := range tests {
+		if blocked := isStreamBlocked(tt.err); blocked != tt.blocked {
+			t.Errorf("isStreamBlocked(%v) = %v, expected %v", tt.err, blocked, tt.blocked)
+		}
+	}
+}
+
+func TestConnectionError(t *testing.T) {
+	tests := []struct {
+		code   connect.Code
+		prefix string
+	}{
+		{connect.CodeUnauthenticated, "authentication failed: check credentials"},
+		{connect.CodePermissionDenied, "authentication failed: the account is disabled"},
+		{connect.CodeUnavailable, "cannot reach endpoint https://fish.example.com"},
+		{connect.CodeDeadlineExceeded, "cannot reach endpoint https://fish.example.com"},
+		{connect.CodeInternal, "API connection failed"},
+	}
+	for _, tt := range tests {
+		err := connectionError("https://fish.example.com", connect.NewError(tt.code, errors.New("test")))
+		if !strings.HasPrefix(err.Error(), tt.prefix) {
+			t.Errorf("code %s: expected error starting with %q, got %q", tt.code, tt.prefix, err)
+		}
+	}
+}
+
+func TestStepConnectAPI_Resume(t *testing.T) {
+	tests := []struct {
+		name   string
+		status aquariumv2.ApplicationState_Status
+		action multistep.StepAction
+	}{
+		{"allocated", aquariumv2.ApplicationState_ALLOCATED, multistep.ActionContinue},
+		{"deallocated", aquariumv2.ApplicationState_DEALLOCATED, multistep.ActionHalt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle(aquariumv2connect.NewUserServiceHandler(&testUserService{}))
+			mux.Handle(aquariumv2connect.NewLabelServiceHandler(&testLabelService{labels: []*aquariumv2.Label{newTestLabel("label-v1", 3)}}))
+			mux.Handle(aquariumv2connect.NewApplicationServiceHandler(&testApplicationService{
+				statuses: []aquariumv2.ApplicationState_Status{tt.status},
+			}))
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			state := newTestState(t, nil)
+			step := &StepConnectAPI{Config: &Config{
+				Endpoint:                  server.URL,
+				ConnectionRetries:         1,
+				ResumeApplicationUID:      "app-uid",
+				connectionTimeoutDuration: time.Minute,
+			}, HTTPClient: server.Client()}
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %v, got %v: %v", tt.action, action, state.Get("error"))
+			}
+			if tt.action != multistep.ActionContinue {
+				return
+			}
+
+			if state.Get("application").(*aquariumv2.Application).GetUid() != "app-uid" {
+				t.Fatalf("application was not loaded")
+			}
+			if state.Get("application_resource").(*aquariumv2.ApplicationResource).GetUid() != "res-uid" {
+				t.Fatalf("application resource was not loaded")
+			}
+			if state.Get("selected_label").(*aquariumv2.Label).GetVersion() != 3 {
+				t.Fatalf("application label was not loaded")
+			}
+			generatedData := state.Get("generated_data").(map[string]any)
+			if generatedData["ApplicationUID"] != "app-uid" || generatedData["ResourceUID"] != "res-uid" || generatedData["LabelVersion"] != "3" {
+				t.Fatalf("unexpected generated data: %v", generatedData)
+			}
+		})
+	}
+}
@@ -16,10 +16,12 @@ package aquarium
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"time"
 
+	connect "connectrpc.com/connect"
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -28,8 +30,7 @@ import (
 
 // StepCreateApplication creates an application in AquariumFish
 type StepCreateApplication struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
 // Run executes the step to create an application
@@ -40,29 +41,36 @@ func (s *StepCreateApplication) Run(ctx context.Context, state multistep.StateBa
 
 	ui.Say("Creating application...")
 
-	// Prepare application metadata
-	metadata := make(map[string]any)
-
-	// Add any user-provided metadata
-	if s.Config.ApplicationMetadata != nil {
-		for k, v := range s.Config.ApplicationMetadata {
-			metadata[k] = v
-		}
+	// Fish doesn't know about definition_index, so it's checked before anything is allocated
+	if err := s.Config.checkDefinitionIndex(selectedLabel); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
 	}
 
-	// Add packer-specific metadata
-	metadata["PACKER_BUILD"] = "true"
-	metadata["PACKER_BUILDER"] = "aquarium"
-	metadata["PACKER_BUILD_TIME"] = time.Now().Format(time.RFC3339)
+	// User keys take precedence over the builder ones, collisions are reported by Prepare
+	metadata, _ := mergeMetadata(s.Config.ApplicationMetadata, s.Config.builderMetadata())
 
 	// Create the application
-	metaStruct, _ := structpb.NewStruct(metadata)
+	metaStruct, err := newStruct(metadata)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to prepare application metadata: %v", err))
+		state.Put("error", fmt.Errorf("invalid application metadata: %v", err))
+		return multistep.ActionHalt
+	}
 	app := &aquariumv2.Application{
-		LabelUid: selectedLabel.GetUid(),
-		Metadata: metaStruct,
+		LabelUid:  selectedLabel.GetUid(),
+		OwnerName: s.Config.Owner,
+		Metadata:  metaStruct,
 	}
 
-	createdApp, err := client.CreateApplication(ctx, app)
+	createdApp, err := s.createApplication(ctx, client, app)
+	if errors.Is(err, errCreateApplicationTimeout) {
+		ui.Error(fmt.Sprintf("Application was not created in %s (create_application_timeout), "+
+			"check Fish availability and its applications list for the leftovers", s.Config.CreateApplicationTimeout))
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to create application: %v", err))
 		state.Put("error", fmt.Errorf("application creation failed: %v", err))
@@ -82,6 +90,90 @@ func (s *StepCreateApplication) Run(ctx context.Context, state multistep.StateBa
 	return multistep.ActionContinue
 }
 
+// Application metadata keys set by the builder, prefixed with metadata_key_prefix
+const (
+	metadataKeyBuild           = "build"
+	metadataKeyBuilder         = "builder"
+	metadataKeyBuildID         = "build_id"
+	metadataKeyBuildName       = "build_name"
+	metadataKeyBuildTime       = "build_time"
+	metadataKeyDefinitionIndex = "definition_index"
+	metadataKeySSHKeypairName  = "ssh_keypair_name"
+)
+
+// metadataKey returns the application metadata key of the builder value
+func (c *Config) metadataKey(name string) string {
+	return c.MetadataKeyPrefix + name
+}
+
+// builderMetadata returns the application metadata set by the builder
+func (c *Config) builderMetadata() map[string]any {
+	metadata := map[string]any{
+		c.metadataKey(metadataKeyBuild):     "true",
+		c.metadataKey(metadataKeyBuilder):   "aquarium",
+		c.metadataKey(metadataKeyBuildID):   c.buildID,
+		c.metadataKey(metadataKeyBuildName): c.PackerBuildName,
+		c.metadataKey(metadataKeyBuildTime): time.Now().Format(time.RFC3339),
+	}
+	if c.DefinitionIndex != nil {
+		metadata[c.metadataKey(metadataKeyDefinitionIndex)] = *c.DefinitionIndex
+	}
+	if c.Communicator.SSHKeyPairName != "" {
+		metadata[c.metadataKey(metadataKeySSHKeypairName)] = c.Communicator.SSHKeyPairName
+	}
+	return metadata
+}
+
+// mergeMetadata adds the builder metadata to the user one and returns the sorted builder keys
+// overridden by the user
+func mergeMetadata(user, builder map[string]any) (map[string]any, []string) {
+	metadata := make(map[string]any, len(user)+len(builder))
+	for k, v := range builder {
+		metadata[k] = v
+	}
+	var collisions []string
+	for k, v := range user {
+		if _, ok := builder[k]; ok {
+			collisions = append(collisions, k)
+		}
+		metadata[k] = v
+	}
+	sort.Strings(collisions)
+	return metadata, collisions
+}
+
+// newStruct converts the map to protobuf struct and names the key with unsupported value type
+func newStruct(data map[string]any) (*structpb.Struct, error) {
+	result := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(data))}
+	for key, value := range data {
+		v, err := structpb.NewValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", key, err)
+		}
+		result.Fields[key] = v
+	}
+	return result, nil
+}
+
+// errCreateApplicationTimeout is returned when the application creation hit create_application_timeout
+var errCreateApplicationTimeout = errors.New("application creation timed out")
+
+// createApplication creates the application limited by create_application_timeout
+func (s *StepCreateApplication) createApplication(ctx context.Context, client *APIClient, app *aquariumv2.Application) (*aquariumv2.Application, error) {
+	if s.Config.createApplicationTimeoutDuration <= 0 {
+		return client.CreateApplication(ctx, app)
+	}
+	createCtx, cancel := context.WithTimeout(ctx, s.Config.createApplicationTimeoutDuration)
+	defer cancel()
+	created, err := client.CreateApplication(createCtx, app)
+	// Connect could report the deadline a moment before the context is done
+	deadline := errors.Is(createCtx.Err(), context.DeadlineExceeded) || connect.CodeOf(err) == connect.CodeDeadlineExceeded
+	if err != nil && ctx.Err() == nil && deadline {
+		return nil, fmt.Errorf("%w after %s: %v", errCreateApplicationTimeout, s.Config.CreateApplicationTimeout, err)
+	}
+	return created, err
+}
+
 // Cleanup performs any necessary cleanup
 func (s *StepCreateApplication) Cleanup(state multistep.StateBag) {
 	// The application cleanup will be handled by StepCleanup
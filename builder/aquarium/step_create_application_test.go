@@ -0,0 +1,155 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepCreateApplication_MetadataMerge(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		prefix   string
+		user     map[string]any
+		expected map[string]string
+	}{
+		{
+			name:   "builder keys are namespaced",
+			prefix: "packer_",
+			user:   map[string]any{"build": "user-build", "PACKER_BUILD_NAME": "user-name"},
+			expected: map[string]string{
+				"build":             "user-build",
+				"PACKER_BUILD_NAME": "user-name",
+				"packer_build":      "true",
+				"packer_builder":    "aquarium",
+				"packer_build_id":   "test-build-id",
+				"packer_build_name": "test-build",
+			},
+		},
+		{
+			name:   "user keys win",
+			prefix: "packer_",
+			user:   map[string]any{"packer_build_name": "user-name"},
+			expected: map[string]string{
+				"packer_builder":    "aquarium",
+				"packer_build_id":   "test-build-id",
+				"packer_build_name": "user-name",
+			},
+		},
+		{
+			name:   "custom prefix",
+			prefix: "ci.",
+			user:   map[string]any{"packer_build_name": "user-name"},
+			expected: map[string]string{
+				"packer_build_name": "user-name",
+				"ci.builder":        "aquarium",
+				"ci.build_name":     "test-build",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			apps := &testApplicationService{}
+			client := newTestFish(t, func(mux *http.ServeMux) {
+				mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+			})
+			state := newTestState(t, client)
+			state.Put("selected_label", newTestLabel("label-v1", 1))
+
+			config := &Config{ApplicationMetadata: tt.user, MetadataKeyPrefix: tt.prefix, buildID: "test-build-id"}
+			config.PackerBuildName = "test-build"
+			step := &StepCreateApplication{Config: config}
+			if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+				t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+			}
+
+			metadata := apps.created[0].GetMetadata().GetFields()
+			for k, v := range tt.expected {
+				if metadata[k].GetStringValue() != v {
+					t.Errorf("metadata %q: expected %q, got %q", k, v, metadata[k].GetStringValue())
+				}
+			}
+			if _, ok := metadata["PACKER_BUILDER"]; ok {
+				t.Errorf("unexpected not prefixed builder key PACKER_BUILDER")
+			}
+		})
+	}
+}
+
+func TestMergeMetadata_Collisions(t *testing.T) {
+	_, collisions := mergeMetadata(
+		map[string]any{"b": 1, "c": 2, "a": 3},
+		map[string]any{"c": 0, "a": 0, "d": 0},
+	)
+	if len(collisions) != 2 || collisions[0] != "a" || collisions[1] != "c" {
+		t.Fatalf("expected sorted collisions [a c], got %v", collisions)
+	}
+}
+
+// hangingApplicationService never responds to the application creation
+type hangingApplicationService struct {
+	aquariumv2connect.UnimplementedApplicationServiceHandler
+}
+
+func (s *hangingApplicationService) Create(ctx context.Context, req *connect.Request[aquariumv2.ApplicationServiceCreateRequest]) (*connect.Response[aquariumv2.ApplicationServiceCreateResponse], error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStepCreateApplication_Timeout(t *testing.T) {
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(&hangingApplicationService{}))
+	})
+	state := newTestState(t, client)
+	state.Put("selected_label", newTestLabel("label-v1", 1))
+
+	step := &StepCreateApplication{Config: &Config{
+		CreateApplicationTimeout:         "50ms",
+		createApplicationTimeoutDuration: 50 * time.Millisecond,
+	}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected halt, got %v", action)
+	}
+	if err := state.Get("error").(error); !errors.Is(err, errCreateApplicationTimeout) {
+		t.Fatalf("expected creation timeout error, got: %v", err)
+	}
+}
+
+// Fish doesn't know about definition_index, so the wrong one shouldn't create the application
+func TestStepCreateApplication_DefinitionIndexOutOfRange(t *testing.T) {
+	apps := &testApplicationService{}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	})
+	state := newTestState(t, client)
+	state.Put("selected_label", newTestLabel("label-v1", 1))
+
+	definitionIndex := 1
+	step := &StepCreateApplication{Config: &Config{DefinitionIndex: &definitionIndex, createApplicationTimeoutDuration: time.Minute}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected halt, got %v", action)
+	}
+	if len(apps.created) != 0 {
+		t.Fatalf("application shouldn't be created: %v", apps.created)
+	}
+}
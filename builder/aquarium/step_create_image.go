@@ -15,21 +15,24 @@
 package aquarium
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
-	"time"
 
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// imageTaskName is the Fish task which creates the image of the resource
+const imageTaskName = "TaskImage"
+
 // StepCreateImage creates an image using the TaskImage functionality
 type StepCreateImage struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
 // Run executes the step to create the image
@@ -41,90 +44,156 @@ func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	ui.Say("Creating image using TaskImage...")
 
 	// Create the image task
-	// TODO: Fix image creation - pass the name of the image to fish
-	options, _ := structpb.NewStruct(map[string]any{})
+	taskOptions := make(map[string]any)
+	for k, v := range s.Config.ImageOptions {
+		taskOptions[k] = v
+	}
+	if s.Config.ImageName != "" {
+		taskOptions["image_name"] = s.Config.ImageName
+	}
+	if len(s.Config.ImageMetadata) > 0 {
+		taskOptions["image_metadata"] = s.Config.ImageMetadata
+	}
+	options, err := newStruct(taskOptions)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to prepare image task options: %v", err))
+		state.Put("error", fmt.Errorf("invalid image task options: %v", err))
+		return multistep.ActionHalt
+	}
 	imageTask := &aquariumv2.ApplicationTask{
 		ApplicationUid: application.GetUid(),
-		Task:           "TaskImage",
+		Task:           imageTaskName,
 		When:           aquariumv2.ApplicationState_DEALLOCATE,
 		Options:        options,
 	}
 
-	// Create the task
-	createdTask, err := client.CreateApplicationTask(ctx, imageTask)
-	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to create image task: %v", err))
-		state.Put("error", fmt.Errorf("image task creation failed: %v", err))
+	// The image task could be reconciled by Fish asynchronously, so looking through all the
+	// application tasks instead of relying only on the created task UID
+	poll := func(ctx context.Context, created *aquariumv2.ApplicationTask) (*aquariumv2.ApplicationTask, error) {
+		tasks, err := client.ListApplicationTasks(ctx, application.GetUid())
+		if err != nil {
+			return nil, err
+		}
+		return findImageTask(tasks, created), nil
+	}
+	currentTask, action := runApplicationTask(ctx, ui, state, s.Config, imageTask, poll)
+	if action != multistep.ActionContinue {
+		return action
+	}
+
+	if err := storeImageResults(ui, state, currentTask); err != nil {
+		ui.Error(fmt.Sprintf("Image creation failed: %v", err))
+		state.Put("error", fmt.Errorf("image creation failed: %v", err))
 		return multistep.ActionHalt
 	}
+	return multistep.ActionContinue
+}
+
+// findImageTask returns the TaskImage task relevant to the created one: the completed task with the
+// same UID or the latest one completed after it was created, otherwise the created task if listed
+func findImageTask(tasks []*aquariumv2.ApplicationTask, created *aquariumv2.ApplicationTask) *aquariumv2.ApplicationTask {
+	var found, completed *aquariumv2.ApplicationTask
+	for _, task := range tasks {
+		if task.GetTask() != imageTaskName {
+			continue
+		}
+		if task.GetUid() == created.GetUid() {
+			found = task
+		} else if created.GetCreatedAt() != nil && task.GetCreatedAt().AsTime().Before(created.GetCreatedAt().AsTime()) {
+			// Tasks created before ours are related to the previous images
+			continue
+		}
+		if len(task.GetResult().GetFields()) == 0 {
+			continue
+		}
+		if task.GetUid() == created.GetUid() {
+			return task
+		}
+		if completed == nil || task.GetCreatedAt().AsTime().After(completed.GetCreatedAt().AsTime()) {
+			completed = task
+		}
+	}
+	if completed != nil {
+		return completed
+	}
+	return found
+}
+
+// ImageResult contains the image information returned by the Fish TaskImage
+type ImageResult struct {
+	UID  string `json:"image"`
+	Name string `json:"image_name"`
+	Path string `json:"image_path"`
+
+	// Supplementary details, drivers are reporting the failures in error field
+	Error  string `json:"error"`
+	Status string `json:"status"`
 
-	ui.Say(fmt.Sprintf("Image task created (UID: %s)", createdTask.GetUid()))
-
-	// Set up timeout for image creation
-	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Minute) // Allow more time for image creation
-	defer cancel()
-
-	ticker := time.NewTicker(15 * time.Second)
-	defer ticker.Stop()
-
-	ui.Say("Waiting for image creation to complete...")
-
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			ui.Error("Image creation timeout reached")
-			state.Put("error", fmt.Errorf("image creation timeout"))
-			return multistep.ActionHalt
-
-		case <-ticker.C:
-			// Get current task status
-			currentTask, err := client.GetApplicationTask(ctx, createdTask.GetUid())
-			if err != nil {
-				ui.Error(fmt.Sprintf("Failed to get task status: %v", err))
-				state.Put("error", fmt.Errorf("failed to get task status: %v", err))
-				return multistep.ActionHalt
-			}
-
-			// Check if task has results (indicating completion)
-			if currentTask.GetResult() != nil && len(currentTask.GetResult().AsMap()) > 0 {
-				ui.Say("Image creation completed!")
-
-				// Check for success/failure in results
-				if status, exists := currentTask.GetResult().AsMap()["status"]; exists {
-					if status == "success" || status == "completed" {
-						ui.Say("Image created successfully")
-
-						// Check for image information in results
-						if imageInfo, exists := currentTask.GetResult().AsMap()["image"]; exists {
-							ui.Say(fmt.Sprintf("Image information: %v", imageInfo))
-						}
-
-						if imagePath, exists := currentTask.GetResult().AsMap()["image_path"]; exists {
-							ui.Say(fmt.Sprintf("Image path: %s", imagePath))
-						}
-
-						// Store image task results
-						state.Put("image_task", currentTask)
-						state.Put("image_results", currentTask.GetResult().AsMap())
-
-						return multistep.ActionContinue
-					} else if status == "failed" || status == "error" {
-						ui.Error(fmt.Sprintf("Image creation failed: %v", currentTask.Result))
-						state.Put("error", fmt.Errorf("image creation failed"))
-						return multistep.ActionHalt
-					}
-				}
-
-				// If no explicit status, assume success if results are present
-				ui.Say("Image creation appears to have completed")
-				state.Put("image_task", currentTask)
-				state.Put("image_results", currentTask.GetResult().AsMap())
-				return multistep.ActionContinue
-			}
-
-			ui.Message("Image creation still in progress...")
+	// Metadata Fish stored on the image, includes image_metadata and the server assigned keys
+	StoredMetadata json.RawMessage `json:"image_metadata"`
+}
+
+// parseImageResult decodes the task result into ImageResult, type-checking the fields
+func parseImageResult(result *structpb.Struct) (*ImageResult, error) {
+	data, err := protojson.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal task result: %v", err)
+	}
+	var image ImageResult
+	if err := json.Unmarshal(data, &image); err != nil {
+		return nil, fmt.Errorf("unable to parse task result: %v", err)
+	}
+	if image.Error != "" {
+		return nil, fmt.Errorf("task reported error: %s", image.Error)
+	}
+	if image.Status == "failed" || image.Status == "error" {
+		return nil, fmt.Errorf("task reported status %q: %s", image.Status, data)
+	}
+	if image.UID == "" {
+		return nil, fmt.Errorf("task completed with unknown result: %s", data)
+	}
+	return &image, nil
+}
+
+// storeImageResults puts the image task results in state and the image info in generated data
+func storeImageResults(ui packersdk.Ui, state multistep.StateBag, task *aquariumv2.ApplicationTask) error {
+	image, err := parseImageResult(task.GetResult())
+	if err != nil {
+		return err
+	}
+	data, err := protojson.Marshal(task.GetResult())
+	if err != nil {
+		return fmt.Errorf("unable to marshal task result: %v", err)
+	}
+
+	ui.Say(fmt.Sprintf("Image created (UID: %s, name: %s)", image.UID, image.Name))
+	if image.Path != "" {
+		ui.Say(fmt.Sprintf("Image path: %s", image.Path))
+	}
+
+	state.Put("image_task", task)
+	state.Put("image_results", task.GetResult().AsMap())
+	state.Put("image_result", image)
+
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["ImageUID"] = image.UID
+	generatedData["ImageName"] = image.Name
+	generatedData["ImagePath"] = image.Path
+	generatedData["ImageStoredMetadata"] = ""
+	if len(image.StoredMetadata) > 0 {
+		// Protojson output is not stable, so making it compact for the generated data
+		var storedMetadata bytes.Buffer
+		if err := json.Compact(&storedMetadata, image.StoredMetadata); err != nil {
+			return fmt.Errorf("unable to read image metadata: %v", err)
 		}
+		generatedData["ImageStoredMetadata"] = storedMetadata.String()
 	}
+	// Full task result for the post-build validation, drivers could return more than the image info
+	generatedData["ImageTaskUID"] = task.GetUid()
+	generatedData["ImageTaskResult"] = string(data)
+	state.Put("generated_data", generatedData)
+
+	return nil
 }
 
 // Cleanup performs any necessary cleanup
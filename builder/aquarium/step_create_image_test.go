@@ -0,0 +1,102 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestTask(uid, name string, created time.Time, result map[string]any) *aquariumv2.ApplicationTask {
+	res, _ := structpb.NewStruct(result)
+	return &aquariumv2.ApplicationTask{
+		Uid:       uid,
+		Task:      name,
+		CreatedAt: timestamppb.New(created),
+		Result:    res,
+	}
+}
+
+func TestFindImageTask(t *testing.T) {
+	now := time.Now()
+	created := newTestTask("task-created", imageTaskName, now, nil)
+	done := map[string]any{"image": "image-uid"}
+
+	tests := []struct {
+		name     string
+		tasks    []*aquariumv2.ApplicationTask
+		expected string
+	}{
+		{"not listed", nil, ""},
+		{"in progress", []*aquariumv2.ApplicationTask{
+			newTestTask("task-created", imageTaskName, now, nil),
+		}, "task-created"},
+		{"completed", []*aquariumv2.ApplicationTask{
+			newTestTask("task-created", imageTaskName, now, done),
+			newTestTask("task-later", imageTaskName, now.Add(time.Minute), done),
+		}, "task-created"},
+		{"reconciled by server", []*aquariumv2.ApplicationTask{
+			newTestTask("task-created", imageTaskName, now, nil),
+			newTestTask("task-server", imageTaskName, now.Add(time.Second), done),
+		}, "task-server"},
+		{"ignores old and other tasks", []*aquariumv2.ApplicationTask{
+			newTestTask("task-old", imageTaskName, now.Add(-time.Hour), done),
+			newTestTask("task-snapshot", "TaskSnapshot", now.Add(time.Second), done),
+		}, ""},
+	}
+	for _, tt := range tests {
+		task := findImageTask(tt.tasks, created)
+		if task.GetUid() != tt.expected {
+			t.Errorf("%s: expected task %q, got %q", tt.name, tt.expected, task.GetUid())
+		}
+	}
+}
+
+func TestStoreImageResults(t *testing.T) {
+	state := newTestState(t, nil)
+	task := newTestTask("task-uid", imageTaskName, time.Now(), map[string]any{
+		"image":      "image-uid",
+		"image_name": "test-image",
+		"checksum":   "sha256:abc",
+		"image_metadata": map[string]any{
+			"team":       "build",
+			"created_by": "fish-node-1",
+		},
+	})
+	if err := storeImageResults(packersdk.TestUi(t), state, task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	generatedData := state.Get("generated_data").(map[string]any)
+	if generatedData["ImageTaskUID"] != "task-uid" {
+		t.Fatalf("unexpected ImageTaskUID: %v", generatedData["ImageTaskUID"])
+	}
+	var result map[string]any
+	if err := json.Unmarshal([]byte(generatedData["ImageTaskResult"].(string)), &result); err != nil {
+		t.Fatalf("ImageTaskResult is not a valid JSON: %v", err)
+	}
+	if result["checksum"] != "sha256:abc" || result["image"] != "image-uid" {
+		t.Fatalf("unexpected ImageTaskResult: %v", result)
+	}
+	if generatedData["ImageStoredMetadata"] != `{"created_by":"fish-node-1","team":"build"}` {
+		t.Fatalf("unexpected ImageStoredMetadata: %v", generatedData["ImageStoredMetadata"])
+	}
+}
@@ -0,0 +1,154 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// StepCreateLabel creates the label from label_definition_file, so StepFindLabel uses it for the build
+type StepCreateLabel struct {
+	Config *Config
+}
+
+// Run executes the step to create the label
+func (s *StepCreateLabel) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	client := state.Get("api_client").(*APIClient)
+	label := proto.Clone(s.Config.labelDefinition).(*aquariumv2.Label)
+
+	ui.Say(fmt.Sprintf("Creating label '%s' from %s...", label.GetName(), s.Config.LabelDefinitionFile))
+
+	existing, err := client.GetLabels(ctx, label.GetName(), "")
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to get existing labels: %v", err))
+		state.Put("error", fmt.Errorf("label retrieval failed: %v", err))
+		return multistep.ActionHalt
+	}
+
+	// Fish labels are immutable, so the same version could only be reused when it's not changed
+	if label.GetVersion() == 0 {
+		for _, l := range existing {
+			label.Version = max(label.GetVersion(), l.GetVersion())
+		}
+		label.Version++
+	}
+	for _, l := range existing {
+		if l.GetVersion() != label.GetVersion() {
+			continue
+		}
+		if !sameLabelDefinition(l, label) {
+			err := fmt.Errorf("label '%s' version %d already exists with different definition, change the version in %s",
+				label.GetName(), label.GetVersion(), s.Config.LabelDefinitionFile)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("Label '%s' version %d already exists with the same definition (UID: %s)",
+			l.GetName(), l.GetVersion(), l.GetUid()))
+		s.Config.LabelUID = l.GetUid()
+		if s.Config.DryRun {
+			selectDryRunLabel(state, l)
+		}
+		return multistep.ActionContinue
+	}
+
+	if s.Config.DryRun {
+		ui.Say(fmt.Sprintf("Dry run: label '%s' version %d would be created", label.GetName(), label.GetVersion()))
+		selectDryRunLabel(state, label)
+		return multistep.ActionContinue
+	}
+
+	created, err := client.CreateLabel(ctx, label)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to create label: %v", err))
+		state.Put("error", fmt.Errorf("label creation failed: %v", err))
+		return multistep.ActionHalt
+	}
+	ui.Say(fmt.Sprintf("Label '%s' version %d created (UID: %s)", created.GetName(), created.GetVersion(), created.GetUid()))
+
+	// StepCleanup removes it after the application deallocation if cleanup_label is set
+	state.Put("created_label", created)
+	s.Config.LabelUID = created.GetUid()
+
+	return multistep.ActionContinue
+}
+
+// selectDryRunLabel stores the label for the next steps instead of StepFindLabel skipped in dry run
+func selectDryRunLabel(state multistep.StateBag, label *aquariumv2.Label) {
+	state.Put("selected_label", label)
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["LabelName"] = label.GetName()
+	generatedData["LabelVersion"] = strconv.Itoa(int(label.GetVersion()))
+	state.Put("generated_data", generatedData)
+}
+
+// sameLabelDefinition compares the labels ignoring the fields set by Fish
+func sameLabelDefinition(a, b *aquariumv2.Label) bool {
+	return proto.Equal(&aquariumv2.Label{Definitions: a.GetDefinitions(), Metadata: a.GetMetadata()},
+		&aquariumv2.Label{Definitions: b.GetDefinitions(), Metadata: b.GetMetadata()})
+}
+
+// loadLabelDefinitionFile parses the YAML or JSON label definition and uses its name as label_name
+func (c *Config) loadLabelDefinitionFile() error {
+	data, err := os.ReadFile(c.LabelDefinitionFile)
+	if err != nil {
+		return fmt.Errorf("unable to read label_definition_file: %v", err)
+	}
+	// JSON is a subset of YAML, so both are handled the same way
+	if data, err = yaml.YAMLToJSON(data); err != nil {
+		return fmt.Errorf("unable to parse label_definition_file %s: %v", c.LabelDefinitionFile, err)
+	}
+	label := &aquariumv2.Label{}
+	if err := protojson.Unmarshal(data, label); err != nil {
+		return fmt.Errorf("invalid label_definition_file %s: %v", c.LabelDefinitionFile, err)
+	}
+
+	if label.GetName() == "" {
+		return fmt.Errorf("label_definition_file %s has no label name", c.LabelDefinitionFile)
+	}
+	if len(label.GetDefinitions()) == 0 {
+		return fmt.Errorf("label_definition_file %s has no definitions", c.LabelDefinitionFile)
+	}
+	if c.LabelName != "" && c.LabelName != label.GetName() {
+		return fmt.Errorf("label_definition_file %s has name '%s' which does not match label_name '%s'",
+			c.LabelDefinitionFile, label.GetName(), c.LabelName)
+	}
+	c.LabelName = label.GetName()
+	if err := c.checkDefinitionIndex(label); err != nil {
+		return fmt.Errorf("label_definition_file %s: %v", c.LabelDefinitionFile, err)
+	}
+
+	// These fields are set by Fish
+	label.Uid = ""
+	label.CreatedAt = nil
+	c.labelDefinition = label
+	return nil
+}
+
+// Cleanup performs any necessary cleanup
+func (s *StepCreateLabel) Cleanup(state multistep.StateBag) {
+	// The label is removed by StepCleanup after the application deallocation
+}
@@ -0,0 +1,121 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+const testLabelDefinition = `
+name: test-label
+definitions:
+  - driver: test
+    resources:
+      cpu: 4
+      ram: 8
+`
+
+// newTestLabelDefinition writes the label definition file and loads it into the config
+func newTestLabelDefinition(t *testing.T, data string) *Config {
+	t.Helper()
+	config := &Config{LabelDefinitionFile: filepath.Join(t.TempDir(), "label.yml")}
+	if err := os.WriteFile(config.LabelDefinitionFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("unable to write label definition: %v", err)
+	}
+	if err := config.loadLabelDefinitionFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return config
+}
+
+func TestStepCreateLabel(t *testing.T) {
+	existing := newTestLabel("label-v2", 2)
+	existing.Definitions = []*aquariumv2.LabelDefinition{
+		{Driver: "test", Resources: &aquariumv2.Resources{Cpu: 4, Ram: 8}},
+	}
+
+	for _, tt := range []struct {
+		name       string
+		definition string
+		action     multistep.StepAction
+		created    int32
+		labelUID   string
+	}{
+		{"next version", testLabelDefinition, multistep.ActionContinue, 3, "created-1"},
+		{"new version", testLabelDefinition + "version: 5\n", multistep.ActionContinue, 5, "created-1"},
+		{"same definition reused", testLabelDefinition + "version: 2\n", multistep.ActionContinue, 0, "label-v2"},
+		{"changed definition", strings.Replace(testLabelDefinition, "cpu: 4", "cpu: 8", 1) + "version: 2\n", multistep.ActionHalt, 0, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			labels := &testLabelService{labels: []*aquariumv2.Label{newTestLabel("label-v1", 1), existing}}
+			client := newTestFish(t, func(mux *http.ServeMux) {
+				mux.Handle(aquariumv2connect.NewLabelServiceHandler(labels))
+			})
+			state := newTestState(t, client)
+
+			config := newTestLabelDefinition(t, tt.definition)
+			step := &StepCreateLabel{Config: config}
+			if action := step.Run(context.Background(), state); action != tt.action {
+				t.Fatalf("expected action %v, got %v: %v", tt.action, action, state.Get("error"))
+			}
+			if config.LabelUID != tt.labelUID {
+				t.Errorf("expected label_uid %q, got %q", tt.labelUID, config.LabelUID)
+			}
+			if tt.created == 0 {
+				if len(labels.created) != 0 {
+					t.Fatalf("label should not be created: %v", labels.created)
+				}
+				return
+			}
+			if len(labels.created) != 1 || labels.created[0].GetVersion() != tt.created {
+				t.Fatalf("expected label version %d created, got: %v", tt.created, labels.created)
+			}
+			if config.LabelName != "test-label" {
+				t.Errorf("expected label_name from definition file, got %q", config.LabelName)
+			}
+		})
+	}
+}
+
+func TestStepCleanup_RemovesCreatedLabel(t *testing.T) {
+	step, state, _ := newTestCleanup(t)
+	labels := &testLabelService{}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(&testApplicationService{}))
+		mux.Handle(aquariumv2connect.NewLabelServiceHandler(labels))
+	})
+	state.Put("api_client", client)
+	state.Put("created_label", &aquariumv2.Label{Uid: "created-1", Name: "test-label", Version: 3})
+
+	step.Cleanup(state)
+	if len(labels.removed) != 0 {
+		t.Fatalf("label should be kept without cleanup_label: %v", labels.removed)
+	}
+
+	step.Config.CleanupLabel = true
+	step.Cleanup(state)
+	if len(labels.removed) != 1 || labels.removed[0] != "created-1" {
+		t.Fatalf("unexpected removed labels: %v", labels.removed)
+	}
+}
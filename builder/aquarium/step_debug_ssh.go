@@ -0,0 +1,86 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepDebugSSH writes the SSH private key to a temp file in packer debug mode so the user can
+// connect to the resource manually, the debug runner pauses right after this step
+type StepDebugSSH struct {
+	Config *Config
+
+	keyPath string
+}
+
+// Run executes the step to expose the SSH connection details in debug mode
+func (s *StepDebugSSH) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Config.PackerDebug {
+		return multistep.ActionContinue
+	}
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if s.Config.SSHUseOTP {
+		ui.Message("Debug: SSH uses one-time credentials, they are requested by the communicator for each connection")
+		return multistep.ActionContinue
+	}
+
+	sshHost, _ := state.GetOk("ssh_host")
+	sshPort, _ := state.GetOk("ssh_port")
+
+	if len(s.Config.Communicator.SSHPrivateKey) == 0 {
+		ui.Message(fmt.Sprintf("Debug: no SSH private key provided, connect by: ssh -p %v %s@%v",
+			sshPort, s.Config.Communicator.SSHUsername, sshHost))
+		return multistep.ActionContinue
+	}
+
+	keyFile, err := os.CreateTemp("", "aquarium-ssh-key-*.pem")
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to create temp file for SSH private key: %v", err))
+		state.Put("error", fmt.Errorf("failed to create debug SSH key file: %v", err))
+		return multistep.ActionHalt
+	}
+	defer keyFile.Close()
+	s.keyPath = keyFile.Name()
+
+	// CreateTemp already uses 0600 permissions
+	if _, err := keyFile.Write(s.Config.Communicator.SSHPrivateKey); err != nil {
+		ui.Error(fmt.Sprintf("Failed to write SSH private key: %v", err))
+		state.Put("error", fmt.Errorf("failed to write debug SSH key file: %v", err))
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Debug: SSH private key saved to %s, connect by: ssh -i %s -p %v %s@%v",
+		s.keyPath, s.keyPath, sshPort, s.Config.Communicator.SSHUsername, sshHost))
+
+	return multistep.ActionContinue
+}
+
+// Cleanup removes the temp SSH private key file
+func (s *StepDebugSSH) Cleanup(state multistep.StateBag) {
+	if s.keyPath == "" {
+		return
+	}
+	if err := os.Remove(s.keyPath); err != nil && !os.IsNotExist(err) {
+		ui := state.Get("ui").(packersdk.Ui)
+		ui.Error(fmt.Sprintf("Failed to remove debug SSH private key %s: %v", s.keyPath, err))
+	}
+}
@@ -0,0 +1,116 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// StepDumpLogs writes the resource details to failure_log_path when the build fails
+//
+// It's placed right after StepCleanup in the chain, so its Cleanup runs before the deallocation
+type StepDumpLogs struct {
+	Config *Config
+}
+
+// Run does nothing, the details are collected during cleanup
+func (s *StepDumpLogs) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+// Cleanup dumps the resource details if the build failed
+func (s *StepDumpLogs) Cleanup(state multistep.StateBag) {
+	if s.Config.FailureLogPath == "" {
+		return
+	}
+	if _, hasError := state.GetOk("error"); !hasError {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	client, hasClient := state.GetOk("api_client")
+	res, hasResource := state.GetOk("application_resource")
+	if !hasClient || !hasResource {
+		ui.Say("No application resource found, skipping the details dump")
+		return
+	}
+	apiClient := client.(*APIClient)
+	resource := res.(*aquariumv2.ApplicationResource)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.connectionTimeoutDuration)
+	defer cancel()
+
+	ui.Say(fmt.Sprintf("Dumping resource %s details to %s...", resource.GetUid(), s.Config.FailureLogPath))
+
+	// Fish has no API for the resource logs, so all the available resource info is dumped
+	var buf bytes.Buffer
+	s.dumpInfo(ctx, &buf, apiClient, resource)
+
+	if err := os.MkdirAll(filepath.Dir(s.Config.FailureLogPath), 0o755); err != nil {
+		ui.Error(fmt.Sprintf("Failed to create directory for failure log: %v", err))
+		return
+	}
+	// Resource and tasks metadata could contain secrets, so the file is readable only by the user
+	if err := os.WriteFile(s.Config.FailureLogPath, buf.Bytes(), 0o600); err != nil {
+		ui.Error(fmt.Sprintf("Failed to write failure log: %v", err))
+		return
+	}
+	// WriteFile doesn't change permissions of the existing file
+	if err := os.Chmod(s.Config.FailureLogPath, 0o600); err != nil {
+		ui.Error(fmt.Sprintf("Failed to set failure log permissions: %v", err))
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Resource details written to %s", s.Config.FailureLogPath))
+}
+
+// dumpInfo writes the resource, application state and tasks results
+func (s *StepDumpLogs) dumpInfo(ctx context.Context, buf *bytes.Buffer, client *APIClient, resource *aquariumv2.ApplicationResource) {
+	write := func(title string, msg proto.Message) {
+		data, err := protojson.MarshalOptions{Multiline: true}.Marshal(msg)
+		if err != nil {
+			fmt.Fprintf(buf, "### %s: unable to marshal: %v\n", title, err)
+			return
+		}
+		fmt.Fprintf(buf, "### %s\n%s\n", title, data)
+	}
+
+	write("Resource", resource)
+
+	if appState, err := client.GetApplicationState(ctx, resource.GetApplicationUid()); err != nil {
+		fmt.Fprintf(buf, "### Application state: unable to get: %v\n", err)
+	} else {
+		write("Application state", appState)
+	}
+
+	tasks, err := client.ListApplicationTasks(ctx, resource.GetApplicationUid())
+	if err != nil {
+		fmt.Fprintf(buf, "### Application tasks: unable to get: %v\n", err)
+		return
+	}
+	for _, task := range tasks {
+		write(fmt.Sprintf("Application task %s (%s)", task.GetTask(), task.GetUid()), task)
+	}
+}
@@ -0,0 +1,53 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+)
+
+// The dump contains the resource metadata, so it shouldn't be readable by others even if it existed
+func TestStepDumpLogs_Permissions(t *testing.T) {
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(&testApplicationService{
+			statuses: []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_ERROR},
+		}))
+	})
+	state := newTestState(t, client)
+	state.Put("error", os.ErrInvalid)
+	state.Put("application_resource", &aquariumv2.ApplicationResource{Uid: "res-uid", ApplicationUid: "app-uid"})
+
+	path := filepath.Join(t.TempDir(), "failure.log")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("unable to create the existing log: %v", err)
+	}
+	step := &StepDumpLogs{Config: &Config{FailureLogPath: path, connectionTimeoutDuration: time.Minute}}
+	step.Cleanup(state)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failure log is not written: %v", err)
+	}
+	if info.Size() == 0 || info.Mode().Perm() != 0o600 {
+		t.Fatalf("unexpected failure log size %d and permissions %v", info.Size(), info.Mode().Perm())
+	}
+}
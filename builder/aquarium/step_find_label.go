@@ -17,18 +17,18 @@ package aquarium
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strconv"
+	"strings"
 
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 // StepFindLabel finds and validates the specified label
 type StepFindLabel struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
 // Run executes the step to find the label
@@ -36,34 +36,134 @@ func (s *StepFindLabel) Run(ctx context.Context, state multistep.StateBag) multi
 	ui := state.Get("ui").(packersdk.Ui)
 	client := state.Get("api_client").(*APIClient)
 
+	// Bounding the lookup so a hung call will not block the build indefinitely
+	lookupCtx, cancel := context.WithTimeout(ctx, s.Config.connectionTimeoutDuration)
+	defer cancel()
+
+	var selectedLabel *aquariumv2.Label
+	var err error
+	if s.Config.LabelUID != "" {
+		selectedLabel, err = s.findByUID(lookupCtx, ui, client)
+	} else {
+		selectedLabel, err = s.findByName(lookupCtx, ui, client)
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Found label '%s' version %d (UID: %s)",
+		selectedLabel.GetName(), selectedLabel.GetVersion(), selectedLabel.GetUid()))
+
+	// Validate that the label has at least one definition
+	if len(selectedLabel.GetDefinitions()) == 0 {
+		ui.Error("Selected label has no definitions")
+		state.Put("error", fmt.Errorf("label has no definitions"))
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Label has %d definition(s) available", len(selectedLabel.GetDefinitions())))
+
+	if err := s.Config.checkDefinitionIndex(selectedLabel); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Store the selected label for other steps
+	state.Put("selected_label", selectedLabel)
+
+	// Update generated data
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["LabelName"] = selectedLabel.GetName()
+	generatedData["LabelVersion"] = strconv.Itoa(int(selectedLabel.GetVersion()))
+	state.Put("generated_data", generatedData)
+
+	return multistep.ActionContinue
+}
+
+// findByUID gets the label directly by UID and verifies it matches label_name if it's set
+func (s *StepFindLabel) findByUID(ctx context.Context, ui packersdk.Ui, client *APIClient) (*aquariumv2.Label, error) {
+	ui.Say(fmt.Sprintf("Looking for label with UID '%s'...", s.Config.LabelUID))
+
+	label, err := client.GetLabel(ctx, s.Config.LabelUID)
+	if err != nil {
+		return nil, fmt.Errorf("label retrieval failed: %v", err)
+	}
+	if label == nil {
+		return nil, fmt.Errorf("label not found: %s", s.Config.LabelUID)
+	}
+	if s.Config.LabelName != "" && label.GetName() != s.Config.LabelName {
+		return nil, fmt.Errorf("label %s has name '%s' which does not match label_name '%s'",
+			s.Config.LabelUID, label.GetName(), s.Config.LabelName)
+	}
+
+	return label, nil
+}
+
+// labelVersionLast is the label_version selecting the latest version, the same as the empty one
+const labelVersionLast = "last"
+
+// findByName lists the labels with label_name and selects the requested or the latest version
+func (s *StepFindLabel) findByName(ctx context.Context, ui packersdk.Ui, client *APIClient) (*aquariumv2.Label, error) {
 	ui.Say(fmt.Sprintf("Looking for label '%s'...", s.Config.LabelName))
 
 	var version string
-	if s.Config.LabelVersion != "" {
+	latest := s.Config.LabelVersion == "" || s.Config.LabelVersion == labelVersionLast
+	if !latest {
 		version = s.Config.LabelVersion
 		ui.Say(fmt.Sprintf("Searching for specific version: %s", version))
-	} else {
-		version = "last" // Get the latest version
+	} else if s.Config.LabelVersionConstraint != "" {
+		// All the versions are needed to find the highest matching one
+		ui.Say(fmt.Sprintf("Searching for the latest version matching: %s", s.Config.LabelVersionConstraint))
+	} else if s.Config.LabelIncludePrerelease {
+		version = labelVersionLast // Get the latest version
 		ui.Say("No version specified, will use the latest version")
+	} else {
+		// The latest version could be a prerelease, so all the versions are needed
+		ui.Say("No version specified, will use the latest non-prerelease version")
 	}
 
 	// Get labels filtered by name and version
 	labels, err := client.GetLabels(ctx, s.Config.LabelName, version)
 	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to retrieve labels: %v", err))
-		state.Put("error", fmt.Errorf("label retrieval failed: %v", err))
-		return multistep.ActionHalt
+		return nil, fmt.Errorf("label retrieval failed: %v", err)
 	}
 
 	if len(labels) == 0 {
-		ui.Error(fmt.Sprintf("No labels found with name '%s'", s.Config.LabelName))
-		state.Put("error", fmt.Errorf("label not found: %s", s.Config.LabelName))
-		return multistep.ActionHalt
+		return nil, fmt.Errorf("label not found: %s", s.Config.LabelName)
+	}
+
+	// The specific version is requested explicitly, so prerelease filter is not applied to it
+	if latest && !s.Config.LabelIncludePrerelease {
+		var releases []*aquariumv2.Label
+		for _, label := range labels {
+			if isPrereleaseLabel(label) {
+				ui.Message(fmt.Sprintf("Skipping prerelease label '%s' version %d (UID: %s)",
+					label.GetName(), label.GetVersion(), label.GetUid()))
+				continue
+			}
+			releases = append(releases, label)
+		}
+		if len(releases) == 0 {
+			return nil, fmt.Errorf("only prerelease versions of label '%s' found, set label_include_prerelease to use them", s.Config.LabelName)
+		}
+		labels = releases
 	}
 
 	// If no specific version was requested, find the latest version
 	var selectedLabel *aquariumv2.Label
-	if s.Config.LabelVersion == "" {
+	if s.Config.LabelVersionConstraint != "" {
+		selectedLabel, err = selectLabelByConstraint(labels, s.Config.LabelVersionConstraint)
+		if err != nil {
+			return nil, err
+		}
+		if selectedLabel == nil {
+			return nil, fmt.Errorf("no version of label '%s' matches constraint '%s'",
+				s.Config.LabelName, s.Config.LabelVersionConstraint)
+		}
+	} else if latest {
 		maxVersion := -1
 		for _, label := range labels {
 			if int(label.GetVersion()) > maxVersion {
@@ -75,9 +175,7 @@ func (s *StepFindLabel) Run(ctx context.Context, state multistep.StateBag) multi
 		// Look for the specific version
 		requestedVersion, err := strconv.Atoi(s.Config.LabelVersion)
 		if err != nil {
-			ui.Error(fmt.Sprintf("Invalid version format '%s': %v", s.Config.LabelVersion, err))
-			state.Put("error", fmt.Errorf("invalid version format: %v", err))
-			return multistep.ActionHalt
+			return nil, fmt.Errorf("invalid version format '%s': %v", s.Config.LabelVersion, err)
 		}
 
 		for _, label := range labels {
@@ -88,37 +186,73 @@ func (s *StepFindLabel) Run(ctx context.Context, state multistep.StateBag) multi
 		}
 
 		if selectedLabel == nil {
-			ui.Error(fmt.Sprintf("Label '%s' version %d not found", s.Config.LabelName, requestedVersion))
-			state.Put("error", fmt.Errorf("label version not found"))
-			return multistep.ActionHalt
+			return nil, fmt.Errorf("label '%s' version %d not found", s.Config.LabelName, requestedVersion)
 		}
 	}
 
 	if selectedLabel == nil {
-		ui.Error(fmt.Sprintf("No suitable label found for '%s'", s.Config.LabelName))
-		state.Put("error", fmt.Errorf("no suitable label found"))
-		return multistep.ActionHalt
+		return nil, fmt.Errorf("no suitable label found for '%s'", s.Config.LabelName)
 	}
 
-	ui.Say(fmt.Sprintf("Found label '%s' version %d (UID: %s)",
-		selectedLabel.GetName(), selectedLabel.GetVersion(), selectedLabel.GetUid()))
+	return selectedLabel, nil
+}
 
-	// Validate that the label has at least one definition
-	if len(selectedLabel.GetDefinitions()) == 0 {
-		ui.Error("Selected label has no definitions")
-		state.Put("error", fmt.Errorf("label has no definitions"))
-		return multistep.ActionHalt
+// prereleaseStatuses are the label metadata "status" values marking the label as prerelease
+var prereleaseStatuses = map[string]bool{
+	"draft":      true,
+	"prerelease": true,
+	"alpha":      true,
+	"beta":       true,
+	"rc":         true,
+}
+
+// isPrereleaseLabel checks the label metadata for "prerelease: true" or prerelease "status"
+func isPrereleaseLabel(label *aquariumv2.Label) bool {
+	metadata := label.GetMetadata().AsMap()
+	if prerelease, ok := metadata["prerelease"].(bool); ok && prerelease {
+		return true
 	}
+	if status, ok := metadata["status"].(string); ok && prereleaseStatuses[strings.ToLower(status)] {
+		return true
+	}
+	return false
+}
 
-	ui.Say(fmt.Sprintf("Label has %d definition(s) available", len(selectedLabel.GetDefinitions())))
+// selectLabelByConstraint returns the label with the highest version matching the semver
+// constraint (ex. ">=3, <5"). Fish label versions are integers, so they are compared as the major
+// semver component.
+func selectLabelByConstraint(labels []*aquariumv2.Label, constraint string) (*aquariumv2.Label, error) {
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label_version_constraint '%s': %v", constraint, err)
+	}
 
-	// Store the selected label for other steps
-	state.Put("selected_label", selectedLabel)
+	var selected *aquariumv2.Label
+	var selectedVersion *version.Version
+	for _, label := range labels {
+		labelVersion, err := version.NewVersion(strconv.Itoa(int(label.GetVersion())))
+		if err != nil || !constraints.Check(labelVersion) {
+			continue
+		}
+		if selectedVersion == nil || labelVersion.GreaterThan(selectedVersion) {
+			selected = label
+			selectedVersion = labelVersion
+		}
+	}
 
-	return multistep.ActionContinue
+	return selected, nil
 }
 
 // Cleanup performs any necessary cleanup
 func (s *StepFindLabel) Cleanup(state multistep.StateBag) {
 	// Nothing to clean up for label lookup
 }
+
+// checkDefinitionIndex makes sure definition_index points to one of the label definitions
+func (c *Config) checkDefinitionIndex(label *aquariumv2.Label) error {
+	if c.DefinitionIndex != nil && *c.DefinitionIndex >= len(label.GetDefinitions()) {
+		return fmt.Errorf("definition_index %d is out of range, label '%s' has %d definition(s)",
+			*c.DefinitionIndex, label.GetName(), len(label.GetDefinitions()))
+	}
+	return nil
+}
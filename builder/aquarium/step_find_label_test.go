@@ -0,0 +1,143 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type testLabelService struct {
+	aquariumv2connect.UnimplementedLabelServiceHandler
+	labels  []*aquariumv2.Label
+	created []*aquariumv2.Label
+	removed []string
+}
+
+func (s *testLabelService) Create(ctx context.Context, req *connect.Request[aquariumv2.LabelServiceCreateRequest]) (*connect.Response[aquariumv2.LabelServiceCreateResponse], error) {
+	label := req.Msg.GetLabel()
+	label.Uid = fmt.Sprintf("created-%d", len(s.created)+1)
+	s.created = append(s.created, label)
+	return connect.NewResponse(&aquariumv2.LabelServiceCreateResponse{Status: true, Data: label}), nil
+}
+
+func (s *testLabelService) Remove(ctx context.Context, req *connect.Request[aquariumv2.LabelServiceRemoveRequest]) (*connect.Response[aquariumv2.LabelServiceRemoveResponse], error) {
+	s.removed = append(s.removed, req.Msg.GetLabelUid())
+	return connect.NewResponse(&aquariumv2.LabelServiceRemoveResponse{Status: true}), nil
+}
+
+func (s *testLabelService) Get(ctx context.Context, req *connect.Request[aquariumv2.LabelServiceGetRequest]) (*connect.Response[aquariumv2.LabelServiceGetResponse], error) {
+	for _, label := range s.labels {
+		if label.GetUid() == req.Msg.GetLabelUid() {
+			return connect.NewResponse(&aquariumv2.LabelServiceGetResponse{Status: true, Data: label}), nil
+		}
+	}
+	return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("label not found"))
+}
+
+func (s *testLabelService) List(ctx context.Context, req *connect.Request[aquariumv2.LabelServiceListRequest]) (*connect.Response[aquariumv2.LabelServiceListResponse], error) {
+	var labels []*aquariumv2.Label
+	for _, label := range s.labels {
+		if req.Msg.Name != nil && label.GetName() != req.Msg.GetName() {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	return connect.NewResponse(&aquariumv2.LabelServiceListResponse{Status: true, Data: labels}), nil
+}
+
+func newTestLabel(uid string, version int32) *aquariumv2.Label {
+	return &aquariumv2.Label{
+		Uid:         uid,
+		Name:        "test-label",
+		Version:     version,
+		Definitions: []*aquariumv2.LabelDefinition{{Driver: "test"}},
+	}
+}
+
+// Makes sure the aquariumv2 types are passed through the state bag between the steps
+func TestStepFindLabel_StateRoundTrip(t *testing.T) {
+	labels := &testLabelService{labels: []*aquariumv2.Label{
+		newTestLabel("label-v1", 1),
+		newTestLabel("label-v3", 3),
+		newTestLabel("label-v2", 2),
+	}}
+	apps := &testApplicationService{}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewLabelServiceHandler(labels))
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	})
+	config := &Config{LabelName: "test-label", connectionTimeoutDuration: time.Minute}
+	state := newTestState(t, client)
+
+	findLabel := &StepFindLabel{Config: config}
+	if action := findLabel.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	label, ok := state.Get("selected_label").(*aquariumv2.Label)
+	if !ok {
+		t.Fatalf("selected_label has unexpected type %T", state.Get("selected_label"))
+	}
+	if label.GetUid() != "label-v3" || label.GetVersion() != 3 {
+		t.Fatalf("expected the latest label version, got %s version %d", label.GetUid(), label.GetVersion())
+	}
+
+	createApp := &StepCreateApplication{Config: config}
+	if action := createApp.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	app, ok := state.Get("application").(*aquariumv2.Application)
+	if !ok {
+		t.Fatalf("application has unexpected type %T", state.Get("application"))
+	}
+	if len(apps.created) != 1 || apps.created[0].GetLabelUid() != "label-v3" {
+		t.Fatalf("application was created with unexpected label: %v", apps.created)
+	}
+	if app.GetUid() != apps.created[0].GetUid() {
+		t.Fatalf("unexpected application UID %q", app.GetUid())
+	}
+}
+
+func TestStepFindLabel_VersionLast(t *testing.T) {
+	prerelease := newTestLabel("label-v4", 4)
+	prerelease.Metadata, _ = structpb.NewStruct(map[string]any{"prerelease": true})
+	labels := &testLabelService{labels: []*aquariumv2.Label{
+		newTestLabel("label-v1", 1),
+		newTestLabel("label-v3", 3),
+		prerelease,
+		newTestLabel("label-v2", 2),
+	}}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewLabelServiceHandler(labels))
+	})
+	state := newTestState(t, client)
+
+	step := &StepFindLabel{Config: &Config{LabelName: "test-label", LabelVersion: "last", connectionTimeoutDuration: time.Minute}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	if label := state.Get("selected_label").(*aquariumv2.Label); label.GetUid() != "label-v3" {
+		t.Fatalf("expected the latest non-prerelease label version, got %s", label.GetUid())
+	}
+}
@@ -0,0 +1,175 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// StepRunTasks executes the configured application tasks one by one
+type StepRunTasks struct {
+	Config *Config
+}
+
+// Run executes the step to run the application tasks
+func (s *StepRunTasks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	client := state.Get("api_client").(*APIClient)
+	application := state.Get("application").(*aquariumv2.Application)
+
+	for _, taskConfig := range s.Config.Tasks {
+		ui.Say(fmt.Sprintf("Running %s...", taskConfig.Task))
+
+		options, err := newStruct(taskConfig.Options)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to prepare %s options: %v", taskConfig.Task, err))
+			state.Put("error", fmt.Errorf("invalid %s options: %v", taskConfig.Task, err))
+			return multistep.ActionHalt
+		}
+		task := &aquariumv2.ApplicationTask{
+			ApplicationUid: application.GetUid(),
+			Task:           taskConfig.Task,
+			When:           taskConfig.when,
+			Options:        options,
+		}
+		// Fish executes the task only when the application reaches its state, which happens after
+		// this step for the other states, so there is no result to wait for during the build
+		if taskConfig.when != aquariumv2.ApplicationState_ALLOCATED {
+			created, err := client.CreateApplicationTask(ctx, task)
+			if err != nil {
+				ui.Error(fmt.Sprintf("Failed to create %s: %v", taskConfig.Task, err))
+				state.Put("error", fmt.Errorf("%s creation failed: %v", taskConfig.Task, err))
+				return multistep.ActionHalt
+			}
+			ui.Say(fmt.Sprintf("%s created (UID: %s), Fish will execute it on %s", taskConfig.Task, created.GetUid(), taskConfig.when))
+			generatedData := state.Get("generated_data").(map[string]any)
+			generatedData[taskResultKey(taskConfig.Task)] = ""
+			state.Put("generated_data", generatedData)
+			continue
+		}
+
+		poll := func(ctx context.Context, created *aquariumv2.ApplicationTask) (*aquariumv2.ApplicationTask, error) {
+			return client.GetApplicationTask(ctx, created.GetUid())
+		}
+		completed, action := runApplicationTask(ctx, ui, state, s.Config, task, poll)
+		if action != multistep.ActionContinue {
+			return action
+		}
+
+		// Fish drivers report the failures in the error field of the result
+		result := completed.GetResult()
+		if errField, ok := result.GetFields()["error"]; ok {
+			ui.Error(fmt.Sprintf("%s failed: %s", taskConfig.Task, errField.GetStringValue()))
+			state.Put("error", fmt.Errorf("%s failed: %s", taskConfig.Task, errField.GetStringValue()))
+			return multistep.ActionHalt
+		}
+		data, err := protojson.Marshal(result)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to marshal %s result: %v", taskConfig.Task, err))
+			state.Put("error", fmt.Errorf("invalid %s result: %v", taskConfig.Task, err))
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("%s completed: %s", taskConfig.Task, data))
+
+		generatedData := state.Get("generated_data").(map[string]any)
+		generatedData[taskResultKey(taskConfig.Task)] = string(data)
+		state.Put("generated_data", generatedData)
+	}
+
+	return multistep.ActionContinue
+}
+
+// taskResultKey returns the generated data key with JSON result of the task, like TaskSnapshotResult
+func taskResultKey(task string) string {
+	return task + "Result"
+}
+
+// runApplicationTask creates the application task and waits until poll returns it with the result
+func runApplicationTask(ctx context.Context, ui packersdk.Ui, state multistep.StateBag, config *Config, task *aquariumv2.ApplicationTask,
+	poll func(ctx context.Context, created *aquariumv2.ApplicationTask) (*aquariumv2.ApplicationTask, error),
+) (*aquariumv2.ApplicationTask, multistep.StepAction) {
+	client := state.Get("api_client").(*APIClient)
+	what := task.GetTask()
+
+	createdTask, err := client.CreateApplicationTask(ctx, task)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to create %s: %v", what, err))
+		state.Put("error", fmt.Errorf("%s creation failed: %v", what, err))
+		return nil, multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("%s created (UID: %s)", what, createdTask.GetUid()))
+
+	// Set up timeout for the task execution
+	timeoutCtx, cancel := context.WithTimeout(ctx, config.imageTimeoutDuration)
+	defer cancel()
+
+	ticker := time.NewTicker(config.imagePollIntervalDuration)
+	defer ticker.Stop()
+	start := time.Now()
+
+	ui.Say(fmt.Sprintf("Waiting for %s to complete...", what))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, haltCancelled(ctx, ui, state, what)
+
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return nil, haltCancelled(ctx, ui, state, what)
+			}
+			ui.Error(fmt.Sprintf("%s timeout reached (%s), increase image_timeout if needed", what, config.ImageTimeout))
+			state.Put("error", fmt.Errorf("%s timeout", what))
+			return nil, multistep.ActionHalt
+
+		case <-ticker.C:
+			currentTask, err := poll(ctx, createdTask)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, haltCancelled(ctx, ui, state, what)
+				}
+				ui.Error(fmt.Sprintf("Failed to get %s status: %v", what, err))
+				state.Put("error", fmt.Errorf("failed to get %s status: %v", what, err))
+				return nil, multistep.ActionHalt
+			}
+			if currentTask == nil {
+				ui.Message(fmt.Sprintf("%s is not listed yet %s", what, waitProgress(start, config.imageTimeoutDuration)))
+				continue
+			}
+
+			// Fish ApplicationTask has no state enum, so the empty result means it's still running
+			if len(currentTask.GetResult().GetFields()) == 0 {
+				ui.Message(fmt.Sprintf("%s in progress %s", what, waitProgress(start, config.imageTimeoutDuration)))
+				continue
+			}
+
+			ui.Say(fmt.Sprintf("[%s] %s completed in %s", time.Now().Format(time.TimeOnly), what, time.Since(start).Round(time.Second)))
+			return currentTask, multistep.ActionContinue
+		}
+	}
+}
+
+// Cleanup performs any necessary cleanup
+func (s *StepRunTasks) Cleanup(state multistep.StateBag) {
+	// The tasks are managed by the AquariumFish system
+}
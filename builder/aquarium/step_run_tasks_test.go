@@ -0,0 +1,70 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepRunTasks(t *testing.T) {
+	var b Builder
+	raw := testConfig()
+	raw["tasks"] = []map[string]any{
+		{"task": "TaskSnapshot"},
+		{"task": "TaskArchive", "when": "DEALLOCATE"},
+	}
+	raw["image_poll_interval"] = "10ms"
+	raw["image_timeout"] = "5s"
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	apps := &testApplicationService{}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	})
+	state := newTestState(t, client)
+	state.Put("application", &aquariumv2.Application{Uid: "app-uid"})
+
+	step := &StepRunTasks{Config: &b.config}
+	start := time.Now()
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	// The DEALLOCATE task result could not be available during the build, so it's not waited for
+	if time.Since(start) > time.Second {
+		t.Fatalf("the tasks took %s to run", time.Since(start))
+	}
+	if len(apps.tasks) != 2 || apps.tasks["task-1"].GetWhen() != aquariumv2.ApplicationState_ALLOCATED {
+		t.Fatalf("unexpected tasks created: %v", apps.tasks)
+	}
+
+	generatedData := state.Get("generated_data").(map[string]any)
+	var result map[string]any
+	if err := json.Unmarshal([]byte(generatedData["TaskSnapshotResult"].(string)), &result); err != nil || result["done"] != true {
+		t.Errorf("unexpected TaskSnapshotResult: %v", generatedData["TaskSnapshotResult"])
+	}
+	if archive, ok := generatedData["TaskArchiveResult"]; !ok || archive != "" {
+		t.Errorf("unexpected TaskArchiveResult: %v", archive)
+	}
+}
@@ -16,22 +16,28 @@ package aquarium
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"net/http"
+	"net"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
 
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"golang.org/x/crypto/ssh"
 )
 
 // StepSetupSSH sets up SSH connectivity using ProxySSH
 type StepSetupSSH struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
-// Run executes the step to setup SSH connectivity
 // Run executes the step to setup SSH connectivity
 func (s *StepSetupSSH) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packersdk.Ui)
@@ -40,46 +46,90 @@ func (s *StepSetupSSH) Run(ctx context.Context, state multistep.StateBag) multis
 
 	ui.Say("Setting up SSH connectivity...")
 
-	// Get SSH access credentials
-	access, err := client.GetApplicationResourceAccess(ctx, resource.GetUid())
-	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to get SSH access credentials: %v", err))
-		state.Put("error", fmt.Errorf("failed to get SSH access: %v", err))
-		return multistep.ActionHalt
+	if s.Config.SSHUseOTP {
+		// Each of the OTP is valid for one connection, so the address and credentials come with the
+		// access requested by the communicator for each connection attempt
+		ui.Say("SSH one-time credentials will be requested for each connection attempt")
+	} else {
+		access, err := client.GetApplicationResourceAccess(ctx, resource.GetUid(), true)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to get SSH access credentials: %v", err))
+			state.Put("error", fmt.Errorf("failed to get SSH access: %v", err))
+			return multistep.ActionHalt
+		}
+
+		ui.Say("SSH access credentials retrieved successfully")
+
+		if err := s.Config.applySSHAccess(state, access, ui.Say); err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	if s.Config.SSHForwardAgent {
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			ui.Message("SSH agent forwarding is enabled, but SSH_AUTH_SOCK is not set so there is no agent to forward")
+		} else {
+			ui.Say("SSH agent forwarding is enabled, the agent keys are usable on the resource during the build")
+		}
 	}
 
-	ui.Say("SSH access credentials retrieved successfully")
+	// Bastion settings are not touched here so the ProxySSH can be reached through the jump host
+
+	ui.Say("SSH connectivity setup completed successfully")
+	return multistep.ActionContinue
+}
 
+// applySSHAccess configures the communicator with the ProxySSH address and credentials and stores
+// the connection details in the state
+func (c *Config) applySSHAccess(state multistep.StateBag, access *aquariumv2.GateProxySSHAccess, say func(string)) error {
 	// Parse the SSH address
 	sshHost, sshPort, err := ParseSSHAddress(access.GetAddress())
 	if err != nil {
-		ui.Say(fmt.Sprintf("Unable to parse SSH address in response %q: %v", access.GetAddress(), err))
-		sshHost = s.Config.Communicator.SSHHost
-		sshPort = s.Config.Communicator.SSHPort
-		ui.Say(fmt.Sprintf("Falling back to communicator defaults: %s:%d", sshHost, sshPort))
+		say(fmt.Sprintf("Unable to parse SSH address in response %q: %v", access.GetAddress(), err))
+		sshHost = c.Communicator.SSHHost
+		sshPort = c.Communicator.SSHPort
+		say(fmt.Sprintf("Falling back to communicator defaults: %s:%d", sshHost, sshPort))
 	}
 
-	ui.Say(fmt.Sprintf("SSH endpoint: %s:%d", sshHost, sshPort))
+	say(fmt.Sprintf("SSH endpoint: %s:%d", sshHost, sshPort))
 
 	// Configure SSH settings based on what's available
 	if access.GetUsername() != "" {
-		s.Config.Communicator.SSHUsername = access.GetUsername()
-		ui.Say(fmt.Sprintf("SSH username: %s", access.GetUsername()))
+		c.Communicator.SSHUsername = access.GetUsername()
+		say(fmt.Sprintf("SSH username: %s", access.GetUsername()))
 	}
 
+	// Credentials are exposed only on demand to not leak them into the CI logs
+	exposeCredentials := c.ExposeSSHCredentials || c.PackerDebug
 	if access.GetPassword() != "" {
-		s.Config.Communicator.SSHPassword = access.GetPassword()
-		ui.Say(fmt.Sprintf("SSH password provided: %s", access.GetPassword()))
-		ui.Say(fmt.Sprintf("You can connect to the Resource by: ssh -p %d %s@%s", sshPort, access.GetUsername(), sshHost))
+		c.Communicator.SSHPassword = access.GetPassword()
+		if exposeCredentials {
+			say(fmt.Sprintf("SSH password provided: %s", access.GetPassword()))
+			say(fmt.Sprintf("You can connect to the Resource by: ssh -p %d %s@%s", sshPort, access.GetUsername(), sshHost))
+		} else {
+			say("SSH password provided")
+		}
 	}
 
-	if access.GetKey() != "" {
-		s.Config.Communicator.SSHPrivateKey = []byte(access.GetKey())
-		ui.Say("SSH private key provided")
+	if c.Communicator.SSHKeyPairName != "" {
+		// The resource is provisioned with the keypair, so ssh_private_key_file is used by communicator
+		say(fmt.Sprintf("Using ssh_private_key_file for keypair %s", c.Communicator.SSHKeyPairName))
+	} else if access.GetKey() != "" {
+		c.Communicator.SSHPrivateKey = []byte(access.GetKey())
+		say("SSH private key provided")
+
+		if c.SSHKeyPath != "" {
+			if err := writeSSHKey(c.SSHKeyPath, access.GetKey()); err != nil {
+				return err
+			}
+			say(fmt.Sprintf("SSH private key written to %s", c.SSHKeyPath))
+		}
 	}
 
 	// Set SSH port
-	s.Config.Communicator.SSHPort = sshPort
+	c.Communicator.SSHPort = sshPort
 
 	// Store SSH connection details in state
 	state.Put("ssh_host", sshHost)
@@ -93,8 +143,88 @@ func (s *StepSetupSSH) Run(ctx context.Context, state multistep.StateBag) multis
 	generatedData["SSHPort"] = strconv.Itoa(sshPort)
 	state.Put("generated_data", generatedData)
 
-	ui.Say("SSH connectivity setup completed successfully")
-	return multistep.ActionContinue
+	return nil
+}
+
+// sshOTPHostFunc requests new one-time access from Fish for each SSH connection attempt, since the
+// OTP is valid just for one connection and the communicator can't reconnect with it. The communicator
+// resolves the host first, so the credentials are applied here for the following SSH config
+func sshOTPHostFunc(config *Config) func(multistep.StateBag) (string, error) {
+	return func(state multistep.StateBag) (string, error) {
+		client := state.Get("api_client").(*APIClient)
+		resource := state.Get("application_resource").(*aquariumv2.ApplicationResource)
+
+		access, err := client.GetApplicationResourceAccess(context.Background(), resource.GetUid(), false)
+		if err != nil {
+			return "", fmt.Errorf("failed to get one-time SSH access: %v", err)
+		}
+		// The connection details are reported only once, not for each of the attempts
+		say := func(string) {}
+		if _, ok := state.GetOk("ssh_access"); !ok {
+			say = state.Get("ui").(packersdk.Ui).Say
+		}
+		if err := config.applySSHAccess(state, access, say); err != nil {
+			return "", err
+		}
+		return host(state)
+	}
+}
+
+// sshHostKeyConfigFunc makes the SSH client to verify the host key against the fingerprint
+func sshHostKeyConfigFunc(fingerprint string, sshConfig func(multistep.StateBag) (*ssh.ClientConfig, error)) func(multistep.StateBag) (*ssh.ClientConfig, error) {
+	return func(state multistep.StateBag) (*ssh.ClientConfig, error) {
+		clientConfig, err := sshConfig(state)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.HostKeyCallback = func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+			if !matchSSHFingerprint(fingerprint, key) {
+				return fmt.Errorf("SSH host key of %s has unexpected fingerprint %s", hostname, ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}
+		return clientConfig, nil
+	}
+}
+
+// validSSHFingerprint checks the fingerprint is in SHA256:<base64> or legacy MD5 aa:bb:... format
+func validSSHFingerprint(fingerprint string) bool {
+	if sha, ok := strings.CutPrefix(fingerprint, "SHA256:"); ok {
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(sha, "="))
+		return err == nil && len(data) == sha256.Size
+	}
+	data, err := hex.DecodeString(strings.ReplaceAll(strings.TrimPrefix(fingerprint, "MD5:"), ":", ""))
+	return err == nil && len(data) == md5.Size
+}
+
+// matchSSHFingerprint compares the key fingerprint in the same format as the expected one
+func matchSSHFingerprint(fingerprint string, key ssh.PublicKey) bool {
+	if sha, ok := strings.CutPrefix(fingerprint, "SHA256:"); ok {
+		return strings.TrimRight(sha, "=") == strings.TrimPrefix(ssh.FingerprintSHA256(key), "SHA256:")
+	}
+	return strings.EqualFold(strings.TrimPrefix(fingerprint, "MD5:"), ssh.FingerprintLegacyMD5(key))
+}
+
+// validateSSHAlgorithms checks the configured algorithms are implemented by the SSH client
+func validateSSHAlgorithms(option string, algorithms, known []string) error {
+	for _, algorithm := range algorithms {
+		if !slices.Contains(known, algorithm) {
+			return fmt.Errorf("%s: algorithm %q is not supported, use one of: %s", option, algorithm, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// writeSSHKey stores the private key in the file readable only by the current user
+func writeSSHKey(path, key string) error {
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		return fmt.Errorf("failed to write SSH private key to %s: %v", path, err)
+	}
+	// WriteFile doesn't change permissions of the existing file
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to set SSH private key %s permissions: %v", path, err)
+	}
+	return nil
 }
 
 // Cleanup performs any necessary cleanup
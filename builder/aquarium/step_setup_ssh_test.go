@@ -0,0 +1,169 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"testing"
+
+	connect "connectrpc.com/connect"
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"golang.org/x/crypto/ssh"
+)
+
+type testGateProxySSH struct {
+	aquariumv2connect.UnimplementedGateProxySSHServiceHandler
+	access *aquariumv2.GateProxySSHAccess
+	// Request received by the last GetResourceAccess call
+	request *aquariumv2.GateProxySSHServiceGetResourceAccessRequest
+	calls   int
+}
+
+func (s *testGateProxySSH) GetResourceAccess(ctx context.Context, req *connect.Request[aquariumv2.GateProxySSHServiceGetResourceAccessRequest]) (*connect.Response[aquariumv2.GateProxySSHServiceGetResourceAccessResponse], error) {
+	s.request = req.Msg
+	s.calls++
+	return connect.NewResponse(&aquariumv2.GateProxySSHServiceGetResourceAccessResponse{Status: true, Data: s.access}), nil
+}
+
+func TestStepSetupSSH_PreservesBastion(t *testing.T) {
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewGateProxySSHServiceHandler(&testGateProxySSH{
+			access: &aquariumv2.GateProxySSHAccess{
+				Address:  "proxy.example.com:1122",
+				Username: "resource-user",
+				Password: "resource-pass",
+			},
+		}))
+	})
+
+	config := &Config{Communicator: communicator.Config{
+		Type: "ssh",
+		SSH: communicator.SSH{
+			SSHBastionHost:     "bastion.example.com",
+			SSHBastionPort:     2222,
+			SSHBastionUsername: "jump",
+			SSHBastionPassword: "jump-pass",
+		},
+	}}
+	state := newTestState(t, client)
+	state.Put("application_resource", &aquariumv2.ApplicationResource{Uid: "resource-uid"})
+
+	step := &StepSetupSSH{Config: config}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+
+	ssh := config.Communicator.SSH
+	if ssh.SSHBastionHost != "bastion.example.com" || ssh.SSHBastionPort != 2222 ||
+		ssh.SSHBastionUsername != "jump" || ssh.SSHBastionPassword != "jump-pass" {
+		t.Fatalf("bastion config was changed: %s:%d %s/%s",
+			ssh.SSHBastionHost, ssh.SSHBastionPort, ssh.SSHBastionUsername, ssh.SSHBastionPassword)
+	}
+	if ssh.SSHUsername != "resource-user" || ssh.SSHPassword != "resource-pass" || ssh.SSHPort != 1122 {
+		t.Fatalf("access credentials are not applied: %s/%s port %d", ssh.SSHUsername, ssh.SSHPassword, ssh.SSHPort)
+	}
+	if host := state.Get("ssh_host"); host != "proxy.example.com" {
+		t.Fatalf("unexpected ssh_host: %v", host)
+	}
+}
+
+// Each OTP is valid for a single connection, so only the communicator attempts should request them
+func TestStepSetupSSH_OTP(t *testing.T) {
+	gate := &testGateProxySSH{access: &aquariumv2.GateProxySSHAccess{
+		Address:  "proxy.example.com:1122",
+		Username: "resource-user",
+		Password: "one-time-pass",
+	}}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewGateProxySSHServiceHandler(gate))
+	})
+
+	config := &Config{SSHUseOTP: true, Communicator: communicator.Config{Type: "ssh"}}
+	state := newTestState(t, client)
+	state.Put("application_resource", &aquariumv2.ApplicationResource{Uid: "resource-uid"})
+
+	step := &StepSetupSSH{Config: config}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	if gate.calls != 0 {
+		t.Fatalf("access shouldn't be requested before the connection, got %d requests", gate.calls)
+	}
+
+	hostFunc := sshOTPHostFunc(config)
+	for attempt := 1; attempt <= 2; attempt++ {
+		host, err := hostFunc(state)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if host != "proxy.example.com" || gate.calls != attempt || gate.request.GetStatic() {
+			t.Fatalf("attempt %d: unexpected host %q, %d requests, static %v", attempt, host, gate.calls, gate.request.GetStatic())
+		}
+	}
+	ssh := config.Communicator.SSH
+	if ssh.SSHUsername != "resource-user" || ssh.SSHPassword != "one-time-pass" || ssh.SSHPort != 1122 {
+		t.Fatalf("access credentials are not applied: %s/%s port %d", ssh.SSHUsername, ssh.SSHPassword, ssh.SSHPort)
+	}
+}
+
+func TestSSHHostKeyConfigFunc(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("unable to create public key: %v", err)
+	}
+	baseConfig := func(multistep.StateBag) (*ssh.ClientConfig, error) {
+		return &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, nil
+	}
+
+	for _, tt := range []struct {
+		fingerprint string
+		match       bool
+	}{
+		{ssh.FingerprintSHA256(key), true},
+		{ssh.FingerprintSHA256(key) + "=", true},
+		{ssh.FingerprintLegacyMD5(key), true},
+		{"MD5:" + strings.ToUpper(ssh.FingerprintLegacyMD5(key)), true},
+		{"SHA256:tHSpmicF4jz5BaSE7G0U71i1a75i6Skng0Zuw2O1By0", false},
+	} {
+		if !validSSHFingerprint(tt.fingerprint) {
+			t.Fatalf("fingerprint %q should be valid", tt.fingerprint)
+		}
+		clientConfig, err := sshHostKeyConfigFunc(tt.fingerprint, baseConfig)(new(multistep.BasicStateBag))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err = clientConfig.HostKeyCallback("proxy.example.com:1122", nil, key)
+		if (err == nil) != tt.match {
+			t.Errorf("fingerprint %q: expected match %v, got error: %v", tt.fingerprint, tt.match, err)
+		}
+	}
+
+	for _, fingerprint := range []string{"SHA256:short", "aa:bb", "not-a-fingerprint"} {
+		if validSSHFingerprint(fingerprint) {
+			t.Errorf("fingerprint %q should be invalid", fingerprint)
+		}
+	}
+}
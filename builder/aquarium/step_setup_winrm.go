@@ -0,0 +1,87 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepSetupWinRM sets up WinRM connectivity to the resource
+//
+// Fish ProxySSH can't be used for WinRM, so the resource is accessed directly by its IP address
+type StepSetupWinRM struct {
+	Config *Config
+}
+
+// Run executes the step to setup WinRM connectivity
+func (s *StepSetupWinRM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	resource := state.Get("application_resource").(*aquariumv2.ApplicationResource)
+
+	ui.Say("Setting up WinRM connectivity...")
+
+	winrmHost := s.Config.Communicator.WinRMHost
+	if winrmHost == "" {
+		winrmHost = resource.GetIpAddr()
+	}
+	if winrmHost == "" {
+		ui.Error("Resource has no IP address and winrm_host is not set")
+		state.Put("error", fmt.Errorf("unable to find WinRM host for resource %s", resource.GetUid()))
+		return multistep.ActionHalt
+	}
+	winrmPort := s.Config.Communicator.WinRMPort
+
+	ui.Say(fmt.Sprintf("WinRM endpoint: %s:%d", winrmHost, winrmPort))
+
+	// Use the resource credentials if they are not set in the template
+	auth := resource.GetAuthentication()
+	if s.Config.Communicator.WinRMUser == "" && auth.GetUsername() != "" {
+		s.Config.Communicator.WinRMUser = auth.GetUsername()
+		ui.Say(fmt.Sprintf("WinRM username: %s", auth.GetUsername()))
+	}
+	if s.Config.Communicator.WinRMPassword == "" && auth.GetPassword() != "" {
+		s.Config.Communicator.WinRMPassword = auth.GetPassword()
+		ui.Say("WinRM password provided")
+	}
+	if s.Config.Communicator.WinRMUser == "" {
+		ui.Error("WinRM username is not provided by the resource and winrm_username is not set")
+		state.Put("error", fmt.Errorf("winrm_username is required"))
+		return multistep.ActionHalt
+	}
+
+	// Store WinRM connection details in state
+	state.Put("winrm_host", winrmHost)
+	state.Put("winrm_port", winrmPort)
+
+	// Update generated data
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["WinRMHost"] = winrmHost
+	generatedData["WinRMPort"] = strconv.Itoa(winrmPort)
+	state.Put("generated_data", generatedData)
+
+	ui.Say("WinRM connectivity setup completed successfully")
+	return multistep.ActionContinue
+}
+
+// Cleanup performs any necessary cleanup
+func (s *StepSetupWinRM) Cleanup(state multistep.StateBag) {
+	// Nothing to clean up specifically for WinRM setup
+}
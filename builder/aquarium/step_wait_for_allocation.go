@@ -16,19 +16,35 @@ package aquarium
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	connect "connectrpc.com/connect"
 	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
+// Fish could report the allocated state before the resource record is available, so it's polled
+// separately from the allocation wait
+const (
+	resourceReadyInterval = 2 * time.Second
+	resourceReadyAttempts = 15
+)
+
+// allocationPollInterval is the pause between the application state checks
+var allocationPollInterval = 5 * time.Second
+
+// errResourceNotMaterialized is returned when the allocated application resource never appeared
+var errResourceNotMaterialized = errors.New("resource never materialized")
+
 // StepWaitForAllocation waits for the application to be allocated
 type StepWaitForAllocation struct {
-	Config     *Config
-	HTTPClient *http.Client
+	Config *Config
 }
 
 // Run executes the step to wait for allocation
@@ -39,17 +55,23 @@ func (s *StepWaitForAllocation) Run(ctx context.Context, state multistep.StateBa
 
 	ui.Say("Waiting for application to be allocated...")
 
-	// Set up timeout
-	timeoutCtx, cancel := context.WithTimeout(ctx, s.Config.allocationTimeoutDuration)
-	defer cancel()
+	// Set up timeout, it's restarted for each recreated application
+	timeout := time.NewTimer(s.Config.allocationTimeoutDuration)
+	defer timeout.Stop()
 
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(allocationPollInterval)
 	defer ticker.Stop()
+	waitStart := time.Now()
+	start := waitStart
 
 	var lastStatus aquariumv2.ApplicationState_Status
+	attempt := 1
 	for {
 		select {
-		case <-timeoutCtx.Done():
+		case <-ctx.Done():
+			return haltCancelled(ctx, ui, state, "allocation wait")
+
+		case <-timeout.C:
 			ui.Error(fmt.Sprintf("Allocation timeout reached (%s)", s.Config.AllocationTimeout))
 			state.Put("error", fmt.Errorf("allocation timeout"))
 			return multistep.ActionHalt
@@ -58,6 +80,9 @@ func (s *StepWaitForAllocation) Run(ctx context.Context, state multistep.StateBa
 			// Get current application state
 			appState, err := client.GetApplicationState(ctx, application.GetUid())
 			if err != nil {
+				if ctx.Err() != nil {
+					return haltCancelled(ctx, ui, state, "allocation wait")
+				}
 				ui.Error(fmt.Sprintf("Failed to get application state: %v", err))
 				state.Put("error", fmt.Errorf("failed to get application state: %v", err))
 				return multistep.ActionHalt
@@ -65,7 +90,9 @@ func (s *StepWaitForAllocation) Run(ctx context.Context, state multistep.StateBa
 
 			// Log status changes
 			if appState.GetStatus() != lastStatus {
-				ui.Say(fmt.Sprintf("Application status: %s - %s", appState.GetStatus().String(), appState.GetDescription()))
+				logDebug("Application %s state transition %s -> %s", application.GetUid(), lastStatus, appState.GetStatus())
+				ui.Say(fmt.Sprintf("[%s] Application status: %s - %s", time.Now().Format(time.TimeOnly),
+					appState.GetStatus().String(), appState.GetDescription()))
 				lastStatus = appState.GetStatus()
 			}
 
@@ -74,39 +101,68 @@ func (s *StepWaitForAllocation) Run(ctx context.Context, state multistep.StateBa
 				ui.Say("Application has been allocated successfully!")
 
 				// Get the application resource
-				resource, err := client.GetApplicationResource(ctx, application.GetUid())
+				resource, err := waitForResource(ctx, client, application.GetUid(), resourceReadyInterval, resourceReadyAttempts)
 				if err != nil {
+					if ctx.Err() != nil {
+						return haltCancelled(ctx, ui, state, "allocation wait")
+					}
+					if errors.Is(err, errResourceNotMaterialized) {
+						ui.Error(fmt.Sprintf("Application %s is allocated, but its resource never materialized in %s",
+							application.GetUid(), resourceReadyInterval*resourceReadyAttempts))
+						state.Put("error", fmt.Errorf("application %s: %w", application.GetUid(), err))
+						return multistep.ActionHalt
+					}
 					ui.Error(fmt.Sprintf("Failed to get application resource: %v", err))
 					state.Put("error", fmt.Errorf("failed to get application resource: %v", err))
 					return multistep.ActionHalt
 				}
 
-				if resource == nil {
-					ui.Say("Application resource not ready yet, continuing to wait...")
-					continue
-				}
-
 				ui.Say(fmt.Sprintf("Application resource ready (UID: %s, IP: %s)",
 					resource.GetUid(), resource.GetIpAddr()))
 
-				// Store the resource for other steps
-				state.Put("application_resource", resource)
+				// Fish picks the first definition some node can fit, so make sure it's the requested one
+				if index := s.Config.DefinitionIndex; index != nil && int(resource.GetDefinitionIndex()) != *index {
+					ui.Error(fmt.Sprintf("Application was allocated with definition %d instead of requested %d",
+						resource.GetDefinitionIndex(), *index))
+					state.Put("error", fmt.Errorf("allocated definition index mismatch: %d != %d",
+						resource.GetDefinitionIndex(), *index))
+					return multistep.ActionHalt
+				}
 
-				// Update generated data
-				generatedData := state.Get("generated_data").(map[string]any)
-				generatedData["ResourceUID"] = resource.GetUid()
-				state.Put("generated_data", generatedData)
+				// Store the resource for other steps
+				storeApplicationResource(state, resource)
+				state.Put("allocation_duration", time.Since(waitStart))
 
 				return multistep.ActionContinue
 
 			case aquariumv2.ApplicationState_ERROR, aquariumv2.ApplicationState_DEALLOCATED, aquariumv2.ApplicationState_DEALLOCATE:
 				ui.Error(fmt.Sprintf("Application failed with status: %s - %s",
 					appState.GetStatus().String(), appState.GetDescription()))
-				state.Put("error", fmt.Errorf("application failed: %s", appState.Status))
-				return multistep.ActionHalt
+				appErr := fmt.Errorf("application failed: %s: %s", appState.GetStatus(), appState.GetDescription())
+				if appState.GetStatus() == aquariumv2.ApplicationState_ERROR {
+					if details := s.taskResults(ctx, ui, client, application.GetUid()); details != "" {
+						appErr = fmt.Errorf("%v (tasks: %s)", appErr, details)
+					}
+				}
+				if attempt > s.Config.AllocationRetries {
+					state.Put("error", appErr)
+					return multistep.ActionHalt
+				}
+
+				ui.Say(fmt.Sprintf("Allocation attempt %d/%d failed, recreating the application...",
+					attempt, s.Config.AllocationRetries+1))
+				attempt++
+				if action := s.recreateApplication(ctx, ui, state, client, application, appState.GetStatus()); action != multistep.ActionContinue {
+					return action
+				}
+				application = state.Get("application").(*aquariumv2.Application)
+				lastStatus = aquariumv2.ApplicationState_UNSPECIFIED
+				timeout.Reset(s.Config.allocationTimeoutDuration)
+				start = time.Now()
 
 			case aquariumv2.ApplicationState_NEW, aquariumv2.ApplicationState_ELECTED:
 				// These are intermediate states, continue waiting
+				ui.Message(fmt.Sprintf("Allocation in progress %s", waitProgress(start, s.Config.allocationTimeoutDuration)))
 				continue
 
 			default:
@@ -117,6 +173,97 @@ func (s *StepWaitForAllocation) Run(ctx context.Context, state multistep.StateBa
 	}
 }
 
+// storeApplicationResource puts the allocated resource in state and its details in generated data
+func storeApplicationResource(state multistep.StateBag, resource *aquariumv2.ApplicationResource) {
+	state.Put("application_resource", resource)
+
+	generatedData := state.Get("generated_data").(map[string]any)
+	generatedData["ResourceUID"] = resource.GetUid()
+	generatedData["ResourceIP"] = resource.GetIpAddr()
+	generatedData["ResourceNodeUID"] = resource.GetNodeUid()
+	generatedData["DefinitionIndex"] = strconv.Itoa(int(resource.GetDefinitionIndex()))
+	// Provisioners can get the metadata values from the flattened JSON with jsondecode
+	generatedData["ResourceMetadata"] = "{}"
+	if len(resource.GetMetadata().GetFields()) > 0 {
+		if metadata, err := protojson.Marshal(resource.GetMetadata()); err == nil {
+			generatedData["ResourceMetadata"] = string(metadata)
+		}
+	}
+	state.Put("generated_data", generatedData)
+}
+
+// recreateApplication deallocates the failed application and creates the new one in its place
+func (s *StepWaitForAllocation) recreateApplication(ctx context.Context, ui packersdk.Ui, state multistep.StateBag, client *APIClient,
+	application *aquariumv2.Application, status aquariumv2.ApplicationState_Status,
+) multistep.StepAction {
+	if status == aquariumv2.ApplicationState_ERROR {
+		if err := client.DeallocateApplication(ctx, application.GetUid()); err != nil {
+			ui.Message(fmt.Sprintf("Unable to deallocate failed application %s: %v", application.GetUid(), err))
+		}
+	}
+
+	return (&StepCreateApplication{Config: s.Config}).Run(ctx, state)
+}
+
+// waitForResource gets the allocated application resource, retrying while it's not available yet
+func waitForResource(ctx context.Context, client *APIClient, appUID string, interval time.Duration, attempts int) (*aquariumv2.ApplicationResource, error) {
+	for attempt := 1; ; attempt++ {
+		resource, err := client.GetApplicationResource(ctx, appUID)
+		if err != nil && connect.CodeOf(err) != connect.CodeNotFound {
+			return nil, err
+		}
+		if err == nil && resource != nil {
+			return resource, nil
+		}
+		if attempt >= attempts {
+			return nil, errResourceNotMaterialized
+		}
+
+		logDebug("Application %s resource is not ready yet (attempt %d/%d)", appUID, attempt, attempts)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitProgress returns the elapsed time of the wait out of its timeout, like "(12m0s / 30m0s)"
+func waitProgress(start time.Time, timeout time.Duration) string {
+	return fmt.Sprintf("(%s / %s)", time.Since(start).Round(time.Second), timeout)
+}
+
+// haltCancelled stops the step when the build was cancelled, StepCleanup will deallocate the application
+func haltCancelled(ctx context.Context, ui packersdk.Ui, state multistep.StateBag, what string) multistep.StepAction {
+	ui.Error(fmt.Sprintf("Build was cancelled during %s", what))
+	state.Put("error", fmt.Errorf("%s cancelled: %v", what, ctx.Err()))
+	return multistep.ActionHalt
+}
+
+// taskResults collects the application tasks results to help with debugging of the failure
+func (s *StepWaitForAllocation) taskResults(ctx context.Context, ui packersdk.Ui, client *APIClient, appUID string) string {
+	tasks, err := client.ListApplicationTasks(ctx, appUID)
+	if err != nil {
+		ui.Message(fmt.Sprintf("Unable to get application tasks: %v", err))
+		return ""
+	}
+
+	var results []string
+	for _, task := range tasks {
+		if len(task.GetResult().GetFields()) == 0 {
+			continue
+		}
+		data, err := protojson.Marshal(task.GetResult())
+		if err != nil {
+			continue
+		}
+		result := fmt.Sprintf("%s: %s", task.GetTask(), data)
+		ui.Message(fmt.Sprintf("Application task %s", result))
+		results = append(results, result)
+	}
+	return strings.Join(results, "; ")
+}
+
 // Cleanup performs any necessary cleanup
 func (s *StepWaitForAllocation) Cleanup(state multistep.StateBag) {
 	// Nothing to clean up specifically for allocation waiting
@@ -0,0 +1,119 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	aquariumv2connect "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2/aquariumv2connect"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestWaitForResource(t *testing.T) {
+	tests := []struct {
+		name          string
+		resourceAfter int
+		calls         int
+		err           error
+	}{
+		{"available", 0, 1, nil},
+		{"lagging", 2, 3, nil},
+		{"never materialized", 10, 3, errResourceNotMaterialized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &testApplicationService{resourceAfter: tt.resourceAfter}
+			client := newTestFish(t, func(mux *http.ServeMux) {
+				mux.Handle(aquariumv2connect.NewApplicationServiceHandler(service))
+			})
+
+			resource, err := waitForResource(context.Background(), client, "app-uid", time.Millisecond, 3)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && resource.GetUid() != "res-uid" {
+				t.Fatalf("unexpected resource: %v", resource)
+			}
+			if service.resourceCalls != tt.calls {
+				t.Fatalf("expected %d resource requests, got %d", tt.calls, service.resourceCalls)
+			}
+		})
+	}
+}
+
+func TestStepWaitForAllocation_TimeoutPerAttempt(t *testing.T) {
+	pollInterval := allocationPollInterval
+	allocationPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { allocationPollInterval = pollInterval })
+
+	// Each of the attempts takes most of allocation_timeout, so they fit only with separate timeouts
+	var statuses []aquariumv2.ApplicationState_Status
+	for _, last := range []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_ERROR, aquariumv2.ApplicationState_ALLOCATED} {
+		for range 20 {
+			statuses = append(statuses, aquariumv2.ApplicationState_NEW)
+		}
+		statuses = append(statuses, last)
+	}
+	apps := &testApplicationService{statuses: statuses}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	})
+	state := newTestState(t, client)
+	state.Put("application", &aquariumv2.Application{Uid: "app-0"})
+	state.Put("selected_label", newTestLabel("label-v1", 1))
+
+	step := &StepWaitForAllocation{Config: &Config{
+		AllocationRetries:         1,
+		allocationTimeoutDuration: 350 * time.Millisecond,
+	}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("unexpected action %v: %v", action, state.Get("error"))
+	}
+	if len(apps.created) != 1 || len(apps.deallocated) != 1 {
+		t.Fatalf("expected the failed application to be recreated, created %d, deallocated %v", len(apps.created), apps.deallocated)
+	}
+}
+
+// Fish could allocate another definition of the label, so the pinned first one has to be checked too
+func TestStepWaitForAllocation_DefinitionIndexMismatch(t *testing.T) {
+	pollInterval := allocationPollInterval
+	allocationPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { allocationPollInterval = pollInterval })
+
+	apps := &testApplicationService{
+		statuses:           []aquariumv2.ApplicationState_Status{aquariumv2.ApplicationState_ALLOCATED},
+		resourceDefinition: 1,
+	}
+	client := newTestFish(t, func(mux *http.ServeMux) {
+		mux.Handle(aquariumv2connect.NewApplicationServiceHandler(apps))
+	})
+	state := newTestState(t, client)
+	state.Put("application", &aquariumv2.Application{Uid: "app-0"})
+	state.Put("selected_label", newTestLabel("label-v1", 1))
+
+	definitionIndex := 0
+	step := &StepWaitForAllocation{Config: &Config{
+		DefinitionIndex:           &definitionIndex,
+		allocationTimeoutDuration: time.Minute,
+	}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected halt on the definition index mismatch, got %v", action)
+	}
+}
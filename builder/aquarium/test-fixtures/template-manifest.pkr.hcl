@@ -0,0 +1,38 @@
+# Copyright 2025 Adobe. All rights reserved.
+# This file is licensed to you under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License. You may obtain a copy
+# of the License at http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software distributed under
+# the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+# OF ANY KIND, either express or implied. See the License for the specific language
+# governing permissions and limitations under the License.
+
+# Author: Sergei Parshev (@sparshev)
+
+# Endpoint and credentials are taken from AQUARIUM_ENDPOINT/USERNAME/PASSWORD environment
+variable "label_name" {
+  type    = string
+  default = env("AQUARIUM_LABEL_NAME")
+}
+
+source "aquarium-rest" "manifest-example" {
+  label_name   = var.label_name
+  ssh_username = "packer"
+
+  ssh_insecure_host_key = true
+}
+
+build {
+  sources = [
+    "source.aquarium-rest.manifest-example"
+  ]
+
+  post-processor "manifest" {
+    output     = "aquarium-manifest.json"
+    strip_path = true
+    custom_data = {
+      label_name = "${build.LabelName}"
+    }
+  }
+}
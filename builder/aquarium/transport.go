@@ -0,0 +1,183 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package aquarium
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// defaultMaxIdleConns is enough to keep the connections of the parallel builds to the same endpoint
+const defaultMaxIdleConns = 100
+
+// HTTP/2 health check: the connection idle for http2ReadIdleTimeout is pinged and closed if there
+// is no response in http2PingTimeout, so the dropped connections are not used by the long waits
+const (
+	http2ReadIdleTimeout = 30 * time.Second
+	http2PingTimeout     = 15 * time.Second
+)
+
+// unixSocketBaseURL is the API base URL used for unix:// endpoint, host is ignored by the socket transport
+const unixSocketBaseURL = "http://localhost"
+
+// NewHTTPClient creates the HTTP client used to communicate with AquariumFish API, it uses the
+// TLS, proxy and transport settings of the config
+func NewHTTPClient(c *Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := newProxyFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Local Fish sidecar listening on Unix socket, the traffic doesn't leave the host so h2c is used
+	if socketPath, ok := unixSocketPath(c.Endpoint); ok {
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+			ReadIdleTimeout: http2ReadIdleTimeout,
+			PingTimeout:     http2PingTimeout,
+		}
+		return &http.Client{Transport: h2c}, nil
+	}
+
+	// Plaintext HTTP/2 (h2c) for local dev/test Fish servers, proxy is not used for it
+	if c.AllowInsecureTransport && strings.HasPrefix(c.Endpoint, "http://") {
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+			ReadIdleTimeout: http2ReadIdleTimeout,
+			PingTimeout:     http2PingTimeout,
+		}
+		return &http.Client{Transport: h2c}, nil
+	}
+
+	// HTTP/2 is required for gRPC protocol and custom TLS config disables it by default
+	tr := &http.Transport{
+		Proxy:             proxy,
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		// All the requests are going to the same endpoint, so the per host idle limit follows the
+		// total one instead of the default 2 which causes the connections churn
+		MaxIdleConns:        c.MaxIdleConns,
+		MaxIdleConnsPerHost: c.MaxIdleConns,
+		MaxConnsPerHost:     c.MaxConnsPerHost,
+		IdleConnTimeout:     c.idleConnTimeoutDuration,
+	}
+	h2, err := http2.ConfigureTransports(tr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure HTTP/2 transport: %v", err)
+	}
+	h2.ReadIdleTimeout = http2ReadIdleTimeout
+	h2.PingTimeout = http2PingTimeout
+	return &http.Client{Transport: tr}, nil
+}
+
+// newTokenHTTPClient creates the HTTP client for the OAuth2 token endpoint with the TLS and proxy
+// settings, the unix socket and h2c API transports are not able to reach it securely
+func newTokenHTTPClient(c *Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := newProxyFunc(c)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:             proxy,
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+	}}, nil
+}
+
+// unixSocketPath returns the socket path of the unix:///path/to/sock endpoint
+func unixSocketPath(endpoint string) (string, bool) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme != "unix" {
+		return "", false
+	}
+	return endpointURL.Path, true
+}
+
+// newProxyFunc returns proxy_url if it's set, otherwise HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+// environment variables are used. The http, https and socks5 proxy schemes are supported.
+func newProxyFunc(c *Config) (func(*http.Request) (*url.URL, error), error) {
+	if c.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy_url is incorrect: %v", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy_url scheme %q is not supported, use http, https or socks5", proxyURL.Scheme)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// newTLSConfig prepares TLS configuration with the custom CA bundle if it's provided
+func newTLSConfig(c *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipTLSVerify,
+	}
+	if c.InsecureSkipTLSVerify || (c.CACertFile == "" && c.CACertPEM == "") {
+		return tlsConfig, nil
+	}
+
+	// Custom CA is added to the system pool to still trust the public endpoints
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if c.CACertFile != "" {
+		data, err := os.ReadFile(c.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca_cert_file: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid PEM certificates found in ca_cert_file %q", c.CACertFile)
+		}
+	}
+	if c.CACertPEM != "" {
+		if !pool.AppendCertsFromPEM([]byte(c.CACertPEM)) {
+			return nil, fmt.Errorf("no valid PEM certificates found in ca_cert_pem")
+		}
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
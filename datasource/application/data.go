@@ -0,0 +1,114 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+// Package application provides data source to get the access to existing Aquarium Fish application
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/adobe/packer-plugin-aquarium/builder/aquarium"
+	"github.com/adobe/packer-plugin-aquarium/datasource/connection"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	connection.Config   `mapstructure:",squash"`
+
+	// UID of the already allocated application to connect to
+	ApplicationUID string `mapstructure:"application_uid" required:"true"`
+}
+
+type DatasourceOutput struct {
+	ResourceUID   string `mapstructure:"resource_uid"`
+	IP            string `mapstructure:"ip"`
+	SSHHost       string `mapstructure:"ssh_host"`
+	SSHPort       int    `mapstructure:"ssh_port"`
+	SSHUsername   string `mapstructure:"ssh_username"`
+	SSHPassword   string `mapstructure:"ssh_password"`
+	SSHPrivateKey string `mapstructure:"ssh_private_key"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...any) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := d.config.Config.Prepare()
+	if d.config.ApplicationUID == "" {
+		errs = append(errs, fmt.Errorf("application_uid is required"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid aquarium-application configuration: %v", errs)
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := d.config.NewAPIClient(d.config.PackerCoreVersion)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	ctx := context.Background()
+	resource, err := client.GetApplicationResource(ctx, d.config.ApplicationUID)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("application %s resource retrieval failed: %v", d.config.ApplicationUID, err)
+	}
+
+	// Static access is requested because the credentials will be used by the build after this call
+	access, err := client.GetApplicationResourceAccess(ctx, resource.GetUid(), true)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("resource %s SSH access retrieval failed: %v", resource.GetUid(), err)
+	}
+
+	sshHost, sshPort, err := aquarium.ParseSSHAddress(access.GetAddress())
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output := DatasourceOutput{
+		ResourceUID:   resource.GetUid(),
+		IP:            resource.GetIpAddr(),
+		SSHHost:       sshHost,
+		SSHPort:       sshPort,
+		SSHUsername:   access.GetUsername(),
+		SSHPassword:   access.GetPassword(),
+		SSHPrivateKey: access.GetKey(),
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+// Package connection contains the Aquarium Fish API connection settings shared by data sources
+package connection
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/adobe/packer-plugin-aquarium/builder/aquarium"
+)
+
+// Config describes how to connect to the Aquarium Fish API, it's squashed into the data sources config
+type Config struct {
+	// AquariumFish API connection settings, AQUARIUM_ENDPOINT/USERNAME/PASSWORD are used if not set
+	Endpoint              string `mapstructure:"endpoint"`
+	Username              string `mapstructure:"username"`
+	Password              string `mapstructure:"password"`
+	InsecureSkipTLSVerify bool   `mapstructure:"insecure_skip_tls_verify"`
+
+	// Bearer token, when set it takes precedence over username/password
+	Token string `mapstructure:"token"`
+
+	// Custom CA bundle to verify the endpoint certificate, as file path or inline PEM
+	CACertFile string `mapstructure:"ca_cert_file"`
+	CACertPEM  string `mapstructure:"ca_cert_pem"`
+
+	// Proxy to reach the endpoint, overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment
+	ProxyURL string `mapstructure:"proxy_url"`
+
+	// Path of the RPC services used when endpoint has no path, "grpc" by default and "/" for the root
+	APIPath string `mapstructure:"api_path"`
+
+	// Timeout for each API request, 1m by default
+	RequestTimeout string `mapstructure:"request_timeout"`
+}
+
+// Prepare fills the defaults and validates the connection settings
+func (c *Config) Prepare() []error {
+	var errs []error
+
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv("AQUARIUM_ENDPOINT")
+	}
+	if c.Username == "" {
+		c.Username = os.Getenv("AQUARIUM_USERNAME")
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv("AQUARIUM_PASSWORD")
+	}
+	if c.RequestTimeout == "" {
+		c.RequestTimeout = "1m"
+	}
+	if c.APIPath == "" {
+		c.APIPath = aquarium.DefaultAPIPath
+	}
+
+	if _, err := url.Parse(c.Endpoint); c.Endpoint == "" || err != nil {
+		errs = append(errs, fmt.Errorf("aquarium endpoint is incorrect: %v", err))
+	}
+	if c.Token == "" && (c.Username == "" || c.Password == "") {
+		errs = append(errs, fmt.Errorf("aquarium username and password are required when token is not set"))
+	}
+
+	if _, err := time.ParseDuration(c.RequestTimeout); err != nil {
+		errs = append(errs, fmt.Errorf("invalid request_timeout: %v", err))
+	}
+
+	return errs
+}
+
+// NewAPIClient creates the API client using the connection settings
+func (c *Config) NewAPIClient(packerCoreVersion string) (*aquarium.APIClient, error) {
+	return aquarium.NewConfiguredAPIClient(&aquarium.Config{
+		Endpoint:              c.Endpoint,
+		Username:              c.Username,
+		Password:              c.Password,
+		Token:                 c.Token,
+		InsecureSkipTLSVerify: c.InsecureSkipTLSVerify,
+		CACertFile:            c.CACertFile,
+		CACertPEM:             c.CACertPEM,
+		ProxyURL:              c.ProxyURL,
+		APIPath:               c.APIPath,
+		RequestTimeout:        c.RequestTimeout,
+		UserAgent:             aquarium.DefaultUserAgent(packerCoreVersion),
+	})
+}
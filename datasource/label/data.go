@@ -0,0 +1,116 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+// Package label provides data source to find the Aquarium Fish label
+package label
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	aquariumv2 "github.com/adobe/aquarium-fish/lib/rpc/proto/aquarium/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/adobe/packer-plugin-aquarium/datasource/connection"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	connection.Config   `mapstructure:",squash"`
+
+	// Name of the label to find
+	Name string `mapstructure:"name" required:"true"`
+	// Version of the label, the latest one is used if not set
+	Version string `mapstructure:"version"`
+}
+
+type DatasourceOutput struct {
+	UID              string `mapstructure:"uid"`
+	Version          int    `mapstructure:"version"`
+	DefinitionsCount int    `mapstructure:"definitions_count"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...any) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := d.config.Config.Prepare()
+	if d.config.Name == "" {
+		errs = append(errs, fmt.Errorf("name is required"))
+	}
+	if d.config.Version != "" {
+		if _, err := strconv.Atoi(d.config.Version); err != nil {
+			errs = append(errs, fmt.Errorf("invalid version %q: %v", d.config.Version, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid aquarium-label configuration: %v", errs)
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client, err := d.config.NewAPIClient(d.config.PackerCoreVersion)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	version := d.config.Version
+	if version == "" {
+		version = "last"
+	}
+	labels, err := client.GetLabels(context.Background(), d.config.Name, version)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("label retrieval failed: %v", err)
+	}
+
+	var selected *aquariumv2.Label
+	for _, label := range labels {
+		if selected == nil || label.GetVersion() > selected.GetVersion() {
+			selected = label
+		}
+	}
+	if selected == nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("label '%s' version %s not found", d.config.Name, version)
+	}
+
+	output := DatasourceOutput{
+		UID:              selected.GetUid(),
+		Version:          int(selected.GetVersion()),
+		DefinitionsCount: len(selected.GetDefinitions()),
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
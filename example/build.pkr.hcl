@@ -75,6 +75,9 @@ source "aquarium-builder" "example" {
   
   # SSH communicator settings
   communicator = "ssh"
+
+  # Verifies the ProxySSH host key, any key is accepted with a warning when it's not set
+  # ssh_host_key_fingerprint = "SHA256:..."
 }
 
 build {
@@ -21,12 +21,18 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
 
 	"github.com/adobe/packer-plugin-aquarium/builder/aquarium"
+	"github.com/adobe/packer-plugin-aquarium/datasource/application"
+	"github.com/adobe/packer-plugin-aquarium/datasource/label"
+	"github.com/adobe/packer-plugin-aquarium/post-processor/hcpregistry"
 	aquariumVersion "github.com/adobe/packer-plugin-aquarium/version"
 )
 
 func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder("rest", new(aquarium.Builder))
+	pps.RegisterDatasource("application", new(application.Datasource))
+	pps.RegisterDatasource("label", new(label.Datasource))
+	pps.RegisterPostProcessor("hcp-registry", new(hcpregistry.PostProcessor))
 	pps.SetVersion(aquariumVersion.PluginVersion)
 	err := pps.Run()
 	if err != nil {
@@ -0,0 +1,53 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package hcpregistry
+
+import (
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+)
+
+// Artifact wraps the builder artifact and exposes the HCP Packer registry metadata
+type Artifact struct {
+	source packer.Artifact
+	images []*registryimage.Image
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.source.BuilderId()
+}
+
+func (a *Artifact) Files() []string {
+	return a.source.Files()
+}
+
+func (a *Artifact) Id() string {
+	return a.source.Id()
+}
+
+func (a *Artifact) String() string {
+	return a.source.String()
+}
+
+func (a *Artifact) State(name string) any {
+	if name == registryimage.ArtifactStateURI {
+		return a.images
+	}
+	return a.source.State(name)
+}
+
+func (a *Artifact) Destroy() error {
+	return a.source.Destroy()
+}
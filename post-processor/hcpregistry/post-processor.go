@@ -0,0 +1,117 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// Package hcpregistry provides post-processor which prepares the Aquarium image metadata for
+// HCP Packer registry. Packer core pushes the metadata to the bucket when HCP is configured.
+package hcpregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+
+	"github.com/adobe/packer-plugin-aquarium/builder/aquarium"
+)
+
+// ProviderName is used as HCP Packer registry provider for the Aquarium images
+const ProviderName = "aquarium"
+
+// Environment variables used by Packer core to connect to HCP
+var hcpEnvVars = []string{"HCP_CLIENT_ID", "HCP_CLIENT_SECRET"}
+
+// registryLabels are the generated data keys stored in the image labels, the rest of them could
+// contain the resource details and metadata which shouldn't get to the registry
+var registryLabels = []string{"ImageUID", "LabelName", "LabelVersion", "Endpoint", "BuildID", "DefinitionIndex"}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// Name of the HCP Packer bucket to store the image in
+	BucketName string `mapstructure:"bucket_name" required:"true"`
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...any) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:  "packer.post-processor.aquarium-hcp-registry",
+		Interpolate: true,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.BucketName == "" {
+		return fmt.Errorf("bucket_name is required")
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source packer.Artifact) (packer.Artifact, bool, bool, error) {
+	if source.BuilderId() != aquarium.BuilderId {
+		return nil, false, false, fmt.Errorf("unsupported artifact type %q, only aquarium builder artifacts are supported", source.BuilderId())
+	}
+
+	for _, env := range hcpEnvVars {
+		if os.Getenv(env) == "" {
+			ui.Say(fmt.Sprintf("%s is not set, skipping HCP Packer registry metadata", env))
+			return source, true, false, nil
+		}
+	}
+
+	// Dry run, skip_create_image or the kept resource don't produce the image to register
+	if source.Id() == "" {
+		ui.Say("No image was created, skipping HCP Packer registry metadata")
+		return source, true, false, nil
+	}
+
+	generatedData, _ := source.State("generated_data").(map[string]any)
+	labels := make(map[string]any)
+	for _, key := range registryLabels {
+		if value, ok := generatedData[key]; ok {
+			labels[key] = value
+		}
+	}
+	image, err := registryimage.FromArtifact(source,
+		registryimage.WithProvider(ProviderName),
+		registryimage.SetLabels(labels),
+		func(img *registryimage.Image) error {
+			img.Labels["bucket_name"] = p.config.BucketName
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("unable to create HCP Packer registry image: %v", err)
+	}
+	if err := image.Validate(); err != nil {
+		return nil, false, false, err
+	}
+
+	ui.Say(fmt.Sprintf("Prepared HCP Packer registry entry for bucket '%s': %s", p.config.BucketName, image))
+
+	return &Artifact{source: source, images: []*registryimage.Image{image}}, true, false, nil
+}
@@ -0,0 +1,72 @@
+/**
+ * Copyright 2025 Adobe. All rights reserved.
+ * This file is licensed to you under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License. You may obtain a copy
+ * of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+ * OF ANY KIND, either express or implied. See the License for the specific language
+ * governing permissions and limitations under the License.
+ */
+
+// Author: Sergei Parshev (@sparshev)
+
+package hcpregistry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+
+	"github.com/adobe/packer-plugin-aquarium/builder/aquarium"
+)
+
+func TestPostProcessor(t *testing.T) {
+	t.Setenv("HCP_CLIENT_ID", "id")
+	t.Setenv("HCP_CLIENT_SECRET", "secret")
+	p := &PostProcessor{config: Config{BucketName: "golden-images"}}
+
+	source := &aquarium.Artifact{ImageUID: "image-uid", StateData: map[string]any{"generated_data": map[string]any{
+		"ImageUID":         "image-uid",
+		"LabelName":        "test-label",
+		"ResourceMetadata": `{"token":"secret"}`,
+	}}}
+	artifact, _, _, err := p.PostProcess(context.Background(), &packersdk.MockUi{}, source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	images := artifact.State(registryimage.ArtifactStateURI).([]*registryimage.Image)
+	expected := map[string]string{"ImageUID": "image-uid", "LabelName": "test-label", "bucket_name": "golden-images"}
+	if len(images) != 1 || len(images[0].Labels) != len(expected) {
+		t.Fatalf("unexpected registry images: %v", images)
+	}
+	for k, v := range expected {
+		if images[0].Labels[k] != v {
+			t.Fatalf("unexpected label %s: %q", k, images[0].Labels[k])
+		}
+	}
+}
+
+// Dry run and the builds without image creation have nothing to register
+func TestPostProcessor_NoImage(t *testing.T) {
+	t.Setenv("HCP_CLIENT_ID", "id")
+	t.Setenv("HCP_CLIENT_SECRET", "secret")
+	p := &PostProcessor{config: Config{BucketName: "golden-images"}}
+
+	ui := &packersdk.MockUi{}
+	source := &aquarium.Artifact{DryRun: true}
+	artifact, keep, _, err := p.PostProcess(context.Background(), ui, source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if artifact != source || !keep {
+		t.Fatalf("expected the source artifact to be kept, got %v", artifact)
+	}
+	if !strings.Contains(ui.SayMessages[0].Message, "No image was created") {
+		t.Fatalf("unexpected messages: %v", ui.SayMessages)
+	}
+}